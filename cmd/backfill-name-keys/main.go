@@ -0,0 +1,112 @@
+// Command backfill-name-keys fills the name keys of existing groups and
+// aliases and the title keys of existing songs, which writes use to match
+// names regardless of case, spacing, quote style and Unicode form. It then
+// lists groups whose keys collide: new songs for such a name are filed under
+// the oldest group, so the others need to be merged into it with
+// POST /groups/{id}/merge. It also lists live songs that duplicate an older
+// song of their group; they keep their old key until resolved by hand.
+//
+// Run it from the repository root once the migrations are applied, and again
+// after changing the normalization rules. It exits with status 2 when
+// collisions are left to resolve.
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/joho/godotenv"
+	"github.com/spf13/viper"
+	"os"
+	"song-library/internal/repository"
+	"song-library/pkg/database"
+	"song-library/pkg/logger"
+	"song-library/pkg/migrations"
+	"strings"
+	"time"
+)
+
+func main() {
+	logger.InitLogger()
+
+	if err := godotenv.Load(); err != nil {
+		logger.Info("No .env file loaded: " + err.Error())
+	}
+
+	if err := initConfig(); err != nil {
+		logger.Error("Error initializing configs: " + err.Error())
+		os.Exit(1)
+	}
+
+	db, err := database.NewPostgresDB(database.Config{
+		Host:     viper.GetString("db.host"),
+		Port:     viper.GetString("db.port"),
+		Username: viper.GetString("db.username"),
+		DBName:   viper.GetString("db.dbname"),
+		SSLMode:  viper.GetString("db.sslmode"),
+		Password: os.Getenv("DB_PASSWORD"),
+	})
+	if err != nil {
+		logger.Error("Failed to initialize database: " + err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := migrations.Migrate(db); err != nil {
+		logger.Error("Error running migrations: " + err.Error())
+		os.Exit(1)
+	}
+
+	repo := repository.NewSongRepository(db, queryTimeouts())
+	result, err := repo.BackfillNameKeys(context.Background())
+	if err != nil {
+		logger.Error("Error backfilling name keys: " + err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("Updated name keys of %d groups and %d aliases and title keys of %d songs.\n", result.Groups, result.Aliases, result.Songs)
+	if len(result.Collisions) == 0 && len(result.TitleCollisions) == 0 {
+		fmt.Println("No collisions found.")
+		return
+	}
+
+	if len(result.Collisions) > 0 {
+		fmt.Printf("%d name keys are shared by several groups. Merge each list into its first group:\n", len(result.Collisions))
+	}
+	for _, collision := range result.Collisions {
+		target := collision.Groups[0]
+		var sources []string
+		for _, group := range collision.Groups {
+			fmt.Printf("  key %q: group %d %q\n", collision.Key, group.ID, group.Name)
+			if group.ID != target.ID {
+				sources = append(sources, fmt.Sprint(group.ID))
+			}
+		}
+		fmt.Printf("  -> POST /groups/%d/merge {\"source_ids\": [%s]}\n", target.ID, strings.Join(sources, ", "))
+	}
+	if len(result.TitleCollisions) > 0 {
+		fmt.Printf("%d songs duplicate another song of their group. Delete or rename them and run the backfill again:\n", len(result.TitleCollisions))
+	}
+	for _, collision := range result.TitleCollisions {
+		song := collision.Song
+		fmt.Printf("  song %d %q by %q duplicates song %d\n", song.ID, song.Song, song.Group, collision.DuplicateOf)
+	}
+	db.Close()
+	os.Exit(2)
+}
+
+func initConfig() error {
+	viper.AddConfigPath("configs")
+	viper.SetConfigName("config")
+	return viper.ReadInConfig()
+}
+
+func queryTimeouts() repository.QueryTimeouts {
+	timeouts := repository.QueryTimeouts{
+		Default:      viper.GetDuration("db.query_timeout"),
+		PerOperation: make(map[string]time.Duration),
+	}
+	for operation := range viper.GetStringMap("db.query_timeouts") {
+		timeouts.PerOperation[operation] = viper.GetDuration("db.query_timeouts." + operation)
+	}
+	return timeouts
+}
@@ -13,9 +13,12 @@ import (
 	"song-library/internal/repository"
 	"song-library/internal/service"
 	"song-library/pkg/database"
+	"song-library/pkg/idempotency"
 	"song-library/pkg/logger"
 	"song-library/pkg/migrations"
+	"song-library/pkg/resilience"
 	"syscall"
+	"time"
 )
 
 // @title Song Library API
@@ -56,9 +59,46 @@ func main() {
 		os.Exit(1)
 	}
 
-	repo := repository.NewSongRepository(db)
-	services := service.NewSongService(repo)
-	handlers := handlers.NewHandler(services)
+	repo := repository.NewSongRepository(db, queryTimeouts())
+	services := service.NewSongService(repo, service.Config{
+		MusicInfo: service.MusicInfoConfig{
+			URL: viper.GetString("music_info.url"),
+			Resilience: resilience.Config{
+				MaxRetries:       viper.GetInt("music_info.max_retries"),
+				BaseBackoff:      viper.GetDuration("music_info.base_backoff"),
+				MaxBackoff:       viper.GetDuration("music_info.max_backoff"),
+				AttemptTimeout:   viper.GetDuration("music_info.attempt_timeout"),
+				FailureThreshold: viper.GetInt("music_info.breaker_threshold"),
+				OpenTimeout:      viper.GetDuration("music_info.breaker_cooldown"),
+			},
+		},
+		CursorSecret:   os.Getenv("CURSOR_SECRET"),
+		FuzzyThreshold: viper.GetFloat64("search.fuzzy_threshold"),
+		BulkMaxItems:   viper.GetInt("bulk.max_items"),
+	})
+	enrichmentWorker := service.NewEnrichmentWorker(services, service.EnrichmentWorkerConfig{
+		Interval:  viper.GetDuration("enrichment.interval"),
+		BatchSize: viper.GetInt("enrichment.batch_size"),
+		RateLimit: viper.GetFloat64("enrichment.rate_limit"),
+	})
+	trashPurger := service.NewTrashPurger(services, service.TrashPurgerConfig{
+		Retention: viper.GetDuration("trash.retention"),
+		Interval:  viper.GetDuration("trash.purge_interval"),
+	})
+	imports := service.NewImportManager(services, service.ImportManagerConfig{
+		MaxUploadBytes: viper.GetInt64("imports.max_upload_bytes"),
+		MaxJobs:        viper.GetInt("imports.max_jobs"),
+	})
+	groups := service.NewGroupService(repo, services)
+	albums := service.NewAlbumService(repo)
+	idempotencyStore := idempotency.NewStore(viper.GetDuration("idempotency.window"))
+	handlers := handlers.NewHandler(services, groups, albums, enrichmentWorker, imports, idempotencyStore)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go enrichmentWorker.Start(workerCtx)
+	go trashPurger.Start(workerCtx)
+	go imports.Start(workerCtx)
 
 	srv := new(app.Server)
 	go func() {
@@ -74,6 +114,7 @@ func main() {
 	<-quit
 
 	logger.Info("Server shutting down")
+	stopWorkers()
 	if err := srv.Shutdown(context.Background()); err != nil {
 		logger.Error("Error occurred during server shutdown: " + err.Error())
 	}
@@ -92,3 +133,14 @@ func initConfig() error {
 
 	return nil
 }
+
+func queryTimeouts() repository.QueryTimeouts {
+	timeouts := repository.QueryTimeouts{
+		Default:      viper.GetDuration("db.query_timeout"),
+		PerOperation: make(map[string]time.Duration),
+	}
+	for operation := range viper.GetStringMap("db.query_timeouts") {
+		timeouts.PerOperation[operation] = viper.GetDuration("db.query_timeouts." + operation)
+	}
+	return timeouts
+}
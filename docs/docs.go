@@ -17,6 +17,103 @@ const docTemplate = `{
         "/": {
             "get": {
                 "summary": "Get all songs",
+                "parameters": [
+                    {
+                        "name": "group",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "description": "Group filter (case-insensitive substring)"
+                    },
+                    {
+                        "name": "song",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "description": "Song filter (case-insensitive substring)"
+                    },
+                    {
+                        "name": "release_from",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "format": "date",
+                        "description": "Released on or after (YYYY-MM-DD)"
+                    },
+                    {
+                        "name": "release_to",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "format": "date",
+                        "description": "Released on or before (YYYY-MM-DD)"
+                    },
+                    {
+                        "name": "year",
+                        "in": "query",
+                        "required": false,
+                        "type": "integer",
+                        "description": "Release year"
+                    },
+                    {
+                        "name": "decade",
+                        "in": "query",
+                        "required": false,
+                        "type": "integer",
+                        "description": "Release decade, given as its first year (e.g. 1990)"
+                    },
+                    {
+                        "name": "has_lyrics",
+                        "in": "query",
+                        "required": false,
+                        "type": "boolean",
+                        "description": "Only songs with (true) or without (false) lyrics"
+                    },
+                    {
+                        "name": "has_link",
+                        "in": "query",
+                        "required": false,
+                        "type": "boolean",
+                        "description": "Only songs with (true) or without (false) a link"
+                    },
+                    {
+                        "name": "link_host",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "description": "Link host or parent domain (e.g. youtube.com)"
+                    },
+                    {
+                        "name": "created_after",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Created after (RFC 3339 or YYYY-MM-DD)"
+                    },
+                    {
+                        "name": "updated_after",
+                        "in": "query",
+                        "required": false,
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Updated after (RFC 3339 or YYYY-MM-DD)"
+                    },
+                    {
+                        "name": "page",
+                        "in": "query",
+                        "required": false,
+                        "type": "integer",
+                        "description": "Page number"
+                    },
+                    {
+                        "name": "limit",
+                        "in": "query",
+                        "required": false,
+                        "type": "integer",
+                        "description": "Limit per page"
+                    }
+                ],
                 "produces": ["application/json"],
                 "responses": {
                     "200": {
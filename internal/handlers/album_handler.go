@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+)
+
+// @Summary List albums
+// @Description List albums ordered by title, each with its number of live tracks
+// @Tags albums
+// @Param title query string false "Title filter"
+// @Param group query string false "Group filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Limit per page" default(10)
+// @Success 200 {object} service.AlbumPage
+// @Failure 400 {object} gin.H{"error": "Invalid page number"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch albums"}
+// @Router /albums [get]
+func (h *Handler) GetAlbums(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	filter := repository.AlbumFilter{Title: c.Query("title"), Group: c.Query("group")}
+	albums, err := h.AlbumService.GetAlbums(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		log.Printf("Error fetching albums: %v", err)
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch albums")
+		return
+	}
+
+	setLinkHeader(c, offsetLinks(page, albums.TotalPages))
+	c.JSON(http.StatusOK, albums)
+}
+
+// @Summary Get an album by ID
+// @Tags albums
+// @Param id path int true "Album ID"
+// @Success 200 {object} models.Album
+// @Failure 400 {object} gin.H{"error": "Invalid album ID"}
+// @Failure 404 {object} gin.H{"error": "Album not found"}
+// @Router /albums/{id} [get]
+func (h *Handler) GetAlbumByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	album, err := h.AlbumService.GetAlbumByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error fetching album with ID %d: %v", id, err)
+		respondAlbumError(c, err, "Could not fetch album")
+		return
+	}
+	c.JSON(http.StatusOK, album)
+}
+
+// @Summary List the tracks of an album
+// @Description List the live songs of an album in disc and track order
+// @Tags albums
+// @Param id path int true "Album ID"
+// @Success 200 {object} gin.H{"tracks": []models.AlbumTrack}
+// @Failure 400 {object} gin.H{"error": "Invalid album ID"}
+// @Failure 404 {object} gin.H{"error": "Album not found"}
+// @Router /albums/{id}/tracks [get]
+func (h *Handler) GetAlbumTracks(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	tracks, err := h.AlbumService.GetAlbumTracks(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error fetching tracks of album %d: %v", id, err)
+		respondAlbumError(c, err, "Could not fetch tracks")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tracks": tracks})
+}
+
+// @Summary Add an album
+// @Description Add an album, creating its group if needed. Tracks place existing live songs, of any group, on the album;
+// @Description each song may appear once and each disc and track number may hold one song.
+// @Tags albums
+// @Param album body service.AlbumRequest true "Album"
+// @Success 201 {object} models.Album
+// @Failure 400 {object} gin.H{"error": "invalid album: title cannot be empty"}
+// @Failure 500 {object} gin.H{"error": "Could not add album"}
+// @Router /albums [post]
+func (h *Handler) AddAlbum(c *gin.Context) {
+	var request service.AlbumRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	album, err := h.AlbumService.AddAlbum(c.Request.Context(), request)
+	if err != nil {
+		log.Printf("Error adding album: %v", err)
+		respondAlbumError(c, err, "Could not add album")
+		return
+	}
+
+	c.Header("Location", "/albums/"+strconv.Itoa(album.ID))
+	c.JSON(http.StatusCreated, album)
+}
+
+// @Summary Update an album
+// @Description Apply a merge patch to an album. Members left out are unchanged, a null release_date clears it
+// @Description and tracks, when present, replace the whole track list.
+// @Tags albums
+// @Param id path int true "Album ID"
+// @Param album body service.AlbumRequest true "Members to change"
+// @Success 200 {object} models.Album
+// @Failure 400 {object} gin.H{"error": "invalid album: unknown field \"name\""}
+// @Failure 404 {object} gin.H{"error": "Album not found"}
+// @Failure 500 {object} gin.H{"error": "Could not update album"}
+// @Router /albums/{id} [patch]
+func (h *Handler) PatchAlbum(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	album, err := h.AlbumService.PatchAlbum(c.Request.Context(), id, patch)
+	if err != nil {
+		log.Printf("Error updating album with ID %d: %v", id, err)
+		respondAlbumError(c, err, "Could not update album")
+		return
+	}
+	c.JSON(http.StatusOK, album)
+}
+
+// @Summary Delete an album
+// @Description Delete an album and its track list. The songs themselves are kept.
+// @Tags albums
+// @Param id path int true "Album ID"
+// @Success 204 "Album deleted"
+// @Failure 400 {object} gin.H{"error": "Invalid album ID"}
+// @Failure 404 {object} gin.H{"error": "Album not found"}
+// @Router /albums/{id} [delete]
+func (h *Handler) DeleteAlbum(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return
+	}
+
+	if err := h.AlbumService.DeleteAlbum(c.Request.Context(), id); err != nil {
+		log.Printf("Error deleting album with ID %d: %v", id, err)
+		respondAlbumError(c, err, "Could not delete album")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondAlbumError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrAlbumNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+	case errors.Is(err, service.ErrInvalidAlbum), errors.Is(err, repository.ErrTrackSongNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondError(c, err, http.StatusInternalServerError, message)
+	}
+}
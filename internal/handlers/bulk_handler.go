@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"song-library/internal/service"
+	"strings"
+)
+
+// maxNDJSONLine bounds a single NDJSON record, which may carry full lyrics.
+const maxNDJSONLine = 1 << 20
+
+var errTooManyItems = errors.New("too many items")
+
+// @Summary Add songs in bulk
+// @Description Add many songs in one request, sent as a JSON array or as NDJSON (application/x-ndjson).
+// @Description Groups are resolved in batch and songs are inserted with multi-row inserts; songs are not enriched.
+// @Description Each item is reported as created, updated, duplicate, invalid or skipped. In all_or_nothing mode a single
+// @Description failing item rejects the whole batch with 422. Songs duplicating a live one are reported as duplicates,
+// @Description or replace it with on_conflict=update; only with on_conflict=error do they reject an all_or_nothing batch.
+// @Tags songs
+// @Accept json
+// @Accept application/x-ndjson
+// @Param songs body []service.SongRequest true "Songs to add"
+// @Param mode query string false "best_effort or all_or_nothing" default(best_effort)
+// @Param on_conflict query string false "error, ignore or update" default(error)
+// @Success 200 {object} service.BulkResult
+// @Failure 400 {object} gin.H{"error": "Invalid request body"}
+// @Failure 413 {object} gin.H{"error": "too many items"}
+// @Failure 422 {object} service.BulkResult
+// @Failure 500 {object} gin.H{"error": "Could not add songs"}
+// @Router /songs/bulk [post]
+func (h *Handler) BulkAddSongs(c *gin.Context) {
+	mode := service.BulkMode(c.DefaultQuery("mode", string(service.BulkBestEffort)))
+	if mode != service.BulkBestEffort && mode != service.BulkAllOrNothing {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode, expected best_effort or all_or_nothing"})
+		return
+	}
+	onConflict, ok := conflictPolicy(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid on_conflict, expected error, ignore or update"})
+		return
+	}
+
+	var items []service.BulkItem
+	var err error
+	switch c.ContentType() {
+	case "application/x-ndjson", "application/ndjson":
+		items, err = readNDJSONItems(c.Request.Body, h.SongService.BulkMaxItems)
+	default:
+		items, err = readJSONArrayItems(c.Request.Body, h.SongService.BulkMaxItems)
+	}
+	if errors.Is(err, errTooManyItems) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	result, err := h.SongService.BulkAddSongs(c.Request.Context(), items, mode, onConflict)
+	if err != nil {
+		log.Printf("Error adding %d songs in bulk: %v", len(items), err)
+		respondError(c, err, http.StatusInternalServerError, "Could not add songs")
+		return
+	}
+
+	if !result.Committed {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// readJSONArrayItems streams the elements of a JSON array. Malformed JSON
+// rejects the request, while elements that are valid JSON but not a valid
+// song become invalid items.
+func readJSONArrayItems(body io.Reader, maxItems int) ([]service.BulkItem, error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON array")
+	}
+
+	var items []service.BulkItem
+	for decoder.More() {
+		if len(items) == maxItems {
+			return nil, fmt.Errorf("%w: at most %d songs per request", errTooManyItems, maxItems)
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		items = append(items, decodeBulkItem(raw))
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// readNDJSONItems reads one song per line, skipping blank lines. Lines that
+// fail to decode become invalid items.
+func readNDJSONItems(body io.Reader, maxItems int) ([]service.BulkItem, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+
+	var items []service.BulkItem
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(items) == maxItems {
+			return nil, fmt.Errorf("%w: at most %d songs per request", errTooManyItems, maxItems)
+		}
+		items = append(items, decodeBulkItem([]byte(line)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func decodeBulkItem(raw []byte) service.BulkItem {
+	var item service.BulkItem
+	if err := json.Unmarshal(raw, &item.Request); err != nil {
+		item.Err = fmt.Errorf("invalid song: %v", err)
+	}
+	return item
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// @Summary Get circuit breaker state
+// @Description Get the state of the circuit breakers guarding outbound calls
+// @Tags debug
+// @Success 200 {object} gin.H{"breakers": []resilience.BreakerStatus}
+// @Router /debug/breakers [get]
+func (h *Handler) GetBreakers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"breakers": h.SongService.BreakerStatus()})
+}
+
+// @Summary Get enrichment job status
+// @Description Get the last run, rows scanned and rows fixed by the background re-enrichment job
+// @Tags debug
+// @Success 200 {object} service.EnrichmentStatus
+// @Router /enrichment/status [get]
+func (h *Handler) GetEnrichmentStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.EnrichmentWorker.Status())
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/repository"
+	"strconv"
+	"strings"
+)
+
+// songETag renders a song version as a strong entity tag.
+func songETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagVersion parses an entity tag produced by songETag. Weak tags are
+// accepted only when weak is set, as If-Match requires strong comparison.
+func etagVersion(tag string, weak bool) (int, bool) {
+	tag = strings.TrimSpace(tag)
+	if strings.HasPrefix(tag, "W/") {
+		if !weak {
+			return 0, false
+		}
+		tag = tag[2:]
+	}
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+var errPreconditionFailed = errors.New("precondition failed")
+
+// ifMatchVersion reads the If-Match header of a write on song id, a "*" or
+// a comma-separated list of entity tags of which weak ones never match. It
+// returns the version the write must find, 0 for any, and whether the write
+// is conditional at all. A precondition that already fails, because no tag
+// is the song's version or the song does not exist, is reported as
+// errPreconditionFailed, which callers answer with 412.
+func (h *Handler) ifMatchVersion(c *gin.Context, id int) (version int, conditional bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	var versions []int
+	if header != "*" {
+		for _, tag := range strings.Split(header, ",") {
+			if v, ok := etagVersion(tag, false); ok {
+				versions = append(versions, v)
+			}
+		}
+		switch len(versions) {
+		case 0:
+			return 0, true, errPreconditionFailed
+		case 1:
+			// The write compares the version itself.
+			return versions[0], true, nil
+		}
+	}
+
+	song, err := h.SongService.GetSongByID(c.Request.Context(), strconv.Itoa(id))
+	if errors.Is(err, repository.ErrSongNotFound) {
+		return 0, true, errPreconditionFailed
+	}
+	if err != nil {
+		return 0, true, err
+	}
+	if header == "*" {
+		return 0, true, nil
+	}
+	for _, v := range versions {
+		if v == song.Version {
+			return v, true, nil
+		}
+	}
+	return 0, true, errPreconditionFailed
+}
+
+// respondPrecondition answers a write whose If-Match header could not be
+// checked.
+func respondPrecondition(c *gin.Context, err error, message string) {
+	if errors.Is(err, errPreconditionFailed) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Song has been modified"})
+		return
+	}
+	log.Printf("Error checking If-Match: %v", err)
+	respondError(c, err, http.StatusInternalServerError, message)
+}
+
+// notModified reports whether the If-None-Match header of a read matches
+// the current version of the song.
+func notModified(c *gin.Context, version int) bool {
+	header := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if v, ok := etagVersion(tag, true); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strings"
+	"testing"
+)
+
+func TestConditionalWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"group": "Muse", "song": "Hysteria", "release_date": "2003-12-01T00:00:00Z", "lyrics": "It's bugging me", "link": "https://example.com/hysteria"}`
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		ifMatch string
+		want    int
+	}{
+		{name: "current tag", method: http.MethodPut, path: "/songs/1", ifMatch: `"1"`, want: http.StatusOK},
+		{name: "stale tag", method: http.MethodPut, path: "/songs/1", ifMatch: `"2"`, want: http.StatusPreconditionFailed},
+		{name: "list holding the current tag", method: http.MethodPut, path: "/songs/1", ifMatch: `"3", "1"`, want: http.StatusOK},
+		{name: "list of stale tags", method: http.MethodPut, path: "/songs/1", ifMatch: `"2", "3"`, want: http.StatusPreconditionFailed},
+		{name: "weak tags never match", method: http.MethodPut, path: "/songs/1", ifMatch: `W/"1"`, want: http.StatusPreconditionFailed},
+		{name: "weak tags are skipped in a list", method: http.MethodDelete, path: "/songs/1", ifMatch: `W/"2", "1"`, want: http.StatusNoContent},
+		{name: "any tag", method: http.MethodDelete, path: "/songs/1", ifMatch: `*`, want: http.StatusNoContent},
+		{name: "malformed tag", method: http.MethodDelete, path: "/songs/1", ifMatch: `1`, want: http.StatusPreconditionFailed},
+		{name: "missing song with a tag", method: http.MethodPut, path: "/songs/9", ifMatch: `"1"`, want: http.StatusPreconditionFailed},
+		{name: "missing song with any tag", method: http.MethodDelete, path: "/songs/9", ifMatch: `*`, want: http.StatusPreconditionFailed},
+		{name: "missing song without If-Match", method: http.MethodDelete, path: "/songs/9", want: http.StatusNotFound},
+		{name: "missing song updated without If-Match", method: http.MethodPut, path: "/songs/9", want: http.StatusNotFound},
+		{name: "missing song with a list", method: http.MethodDelete, path: "/songs/9", ifMatch: `"1", "2"`, want: http.StatusPreconditionFailed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			songs := service.NewSongService(repository.NewMemorySongRepository(), service.Config{CursorSecret: "test"})
+			router := (&Handler{SongService: songs}).InitRoutes()
+
+			add := httptest.NewRequest(http.MethodPost, "/songs/", strings.NewReader(body))
+			add.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, add)
+			if recorder.Code != http.StatusCreated {
+				t.Fatalf("add song: %d %s", recorder.Code, recorder.Body)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			recorder = httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want {
+				t.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, tc.want)
+			}
+		})
+	}
+}
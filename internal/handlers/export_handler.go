@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"time"
+)
+
+// exportWriteTimeout is how long a single write of an export may block on a
+// slow client. It replaces the server-wide write timeout, which would
+// otherwise cut off large exports.
+const exportWriteTimeout = 30 * time.Second
+
+var exportContentTypes = map[service.ExportFormat]string{
+	service.ExportCSV:    "text/csv; charset=utf-8",
+	service.ExportNDJSON: "application/x-ndjson",
+	service.ExportJSON:   "application/json; charset=utf-8",
+}
+
+// exportWriter pushes the write deadline forward before every write.
+type exportWriter struct {
+	gin.ResponseWriter
+	controller *http.ResponseController
+}
+
+func (w exportWriter) Write(data []byte) (int, error) {
+	w.controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	return w.ResponseWriter.Write(data)
+}
+
+// @Summary Export songs
+// @Description Stream every song matching the filters as CSV, NDJSON or a JSON array, using chunked transfer encoding.
+// @Description Accepts the same filters and sort as GET /songs. The CSV output can be uploaded to POST /imports as is.
+// @Description The X-Export-Status trailer is "complete" once every song was written, or "failed" when the export was cut short.
+// @Tags songs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce json
+// @Param format query string false "csv, ndjson or json" default(json)
+// @Param lyrics query bool false "Include lyrics" default(true)
+// @Param group query string false "Group filter"
+// @Param song query string false "Song filter"
+// @Param release_from query string false "Released on or after (YYYY-MM-DD)"
+// @Param release_to query string false "Released on or before (YYYY-MM-DD)"
+// @Param year query int false "Release year"
+// @Param decade query int false "Release decade, given as its first year (e.g. 1990)"
+// @Param has_lyrics query bool false "Only songs with (true) or without (false) lyrics"
+// @Param has_link query bool false "Only songs with (true) or without (false) a link"
+// @Param link_host query string false "Link host or parent domain (e.g. youtube.com)"
+// @Param created_after query string false "Created after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_after query string false "Updated after (RFC 3339 or YYYY-MM-DD)"
+// @Param sort query string false "Comma separated sort keys; prefix with - for descending" default(id)
+// @Success 200 {file} file
+// @Failure 400 {object} gin.H{"error": "Invalid format, expected csv, ndjson or json"}
+// @Failure 500 {object} gin.H{"error": "Could not export songs"}
+// @Failure 504 {object} gin.H{"error": "Request timed out"}
+// @Router /songs/export [get]
+func (h *Handler) ExportSongs(c *gin.Context) {
+	format := service.ExportFormat(c.DefaultQuery("format", string(service.ExportJSON)))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected csv, ndjson or json"})
+		return
+	}
+	includeLyrics, err := boolParam(c, "lyrics")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := parseSongFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sort, err := repository.ParseSongSort(c.DefaultQuery("sort", "id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          err.Error(),
+			"allowed_fields": repository.SortableFields,
+		})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="songs.`+string(format)+`"`)
+	c.Header("Trailer", "X-Export-Status")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	writer := exportWriter{ResponseWriter: c.Writer, controller: http.NewResponseController(c.Writer)}
+	err = h.SongService.ExportSongs(c.Request.Context(), writer, service.ExportOptions{
+		Format:     format,
+		Filter:     filter,
+		Sort:       sort,
+		OmitLyrics: includeLyrics != nil && !*includeLyrics,
+	})
+	if err == nil {
+		c.Writer.Header().Set("X-Export-Status", "complete")
+		return
+	}
+
+	log.Printf("Error exporting songs: %v", err)
+	if !c.Writer.Written() {
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.Header("Trailer", "")
+		respondError(c, err, http.StatusInternalServerError, "Could not export songs")
+		return
+	}
+	// The status line is already out, so the trailer is the only way left to
+	// tell the client the export is incomplete.
+	c.Writer.Header().Set("X-Export-Status", "failed")
+}
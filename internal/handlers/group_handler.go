@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+)
+
+// @Summary List groups
+// @Description List groups ordered by name, each with its number of live songs
+// @Tags groups
+// @Param name query string false "Name filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Limit per page" default(10)
+// @Success 200 {object} service.GroupPage
+// @Failure 400 {object} gin.H{"error": "Invalid page number"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch groups"}
+// @Router /groups [get]
+func (h *Handler) GetGroups(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	groups, err := h.GroupService.GetGroups(c.Request.Context(), c.Query("name"), page, limit)
+	if err != nil {
+		log.Printf("Error fetching groups: %v", err)
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch groups")
+		return
+	}
+
+	setLinkHeader(c, offsetLinks(page, groups.TotalPages))
+	c.JSON(http.StatusOK, groups)
+}
+
+// @Summary Get a group by ID
+// @Tags groups
+// @Param id path int true "Group ID"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} gin.H{"error": "Invalid group ID"}
+// @Failure 404 {object} gin.H{"error": "Group not found"}
+// @Router /groups/{id} [get]
+func (h *Handler) GetGroupByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	group, err := h.GroupService.GetGroupByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error fetching group with ID %d: %v", id, err)
+		respondGroupError(c, err, "Could not fetch group")
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// @Summary List the songs of a group
+// @Description List the live songs of a group. Accepts the same filters, sort and paging as GET /songs and always returns a paginated envelope.
+// @Tags groups
+// @Param id path int true "Group ID"
+// @Param song query string false "Song filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Limit per page" default(10)
+// @Param sort query string false "Comma separated sort keys; prefix with - for descending" default(id)
+// @Success 200 {object} service.SongPage
+// @Failure 400 {object} gin.H{"error": "Invalid group ID"}
+// @Failure 404 {object} gin.H{"error": "Group not found"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch songs"}
+// @Router /groups/{id}/songs [get]
+func (h *Handler) GetGroupSongs(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+	filter, err := parseSongFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+	sort, err := repository.ParseSongSort(c.DefaultQuery("sort", "id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          err.Error(),
+			"allowed_fields": repository.SortableFields,
+		})
+		return
+	}
+
+	songs, err := h.GroupService.GetGroupSongs(c.Request.Context(), id, filter, sort, page, limit)
+	if err != nil {
+		log.Printf("Error fetching songs of group %d: %v", id, err)
+		respondGroupError(c, err, "Could not fetch songs")
+		return
+	}
+
+	setLinkHeader(c, offsetLinks(page, songs.TotalPages))
+	if songs.Items == nil {
+		songs.Items = []models.Song{}
+	}
+	c.JSON(http.StatusOK, songs)
+}
+
+// @Summary Add a group
+// @Tags groups
+// @Param group body service.GroupRequest true "Group"
+// @Success 201 {object} models.Group
+// @Failure 400 {object} gin.H{"error": "invalid group name: name cannot be empty"}
+// @Failure 409 {object} gin.H{"error": "group already exists"}
+// @Router /groups [post]
+func (h *Handler) AddGroup(c *gin.Context) {
+	var request service.GroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	group, err := h.GroupService.AddGroup(c.Request.Context(), request)
+	if err != nil {
+		log.Printf("Error adding group: %v", err)
+		respondGroupError(c, err, "Could not add group")
+		return
+	}
+
+	c.Header("Location", "/groups/"+strconv.Itoa(group.ID))
+	c.JSON(http.StatusCreated, group)
+}
+
+// @Summary Rename a group
+// @Description Rename a group. The new name must not belong to another group.
+// @Tags groups
+// @Param id path int true "Group ID"
+// @Param group body service.GroupRequest true "New name"
+// @Success 200 {object} models.Group
+// @Failure 400 {object} gin.H{"error": "Invalid group ID"}
+// @Failure 404 {object} gin.H{"error": "Group not found"}
+// @Failure 409 {object} gin.H{"error": "group already exists"}
+// @Router /groups/{id} [patch]
+func (h *Handler) RenameGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+	var request service.GroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	group, err := h.GroupService.RenameGroup(c.Request.Context(), id, request)
+	if err != nil {
+		log.Printf("Error renaming group with ID %d: %v", id, err)
+		respondGroupError(c, err, "Could not rename group")
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// @Summary Delete a group
+// @Description Delete a group. Deleting a group also deletes all of its songs, including those in the trash,
+// @Description so a group that still has songs is only deleted with force=true.
+// @Tags groups
+// @Param id path int true "Group ID"
+// @Param force query bool false "Also delete the group's songs" default(false)
+// @Success 200 {object} gin.H{"deleted_songs": int}
+// @Failure 400 {object} gin.H{"error": "Invalid group ID"}
+// @Failure 404 {object} gin.H{"error": "Group not found"}
+// @Failure 409 {object} gin.H{"error": "group still has songs: 3 songs, including any in the trash"}
+// @Router /groups/{id} [delete]
+func (h *Handler) DeleteGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+	force, err := boolParam(c, "force")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deleted, err := h.GroupService.DeleteGroup(c.Request.Context(), id, force != nil && *force)
+	if err != nil {
+		log.Printf("Error deleting group with ID %d: %v", id, err)
+		respondGroupError(c, err, "Could not delete group")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted_songs": deleted})
+}
+
+// @Summary Merge groups
+// @Description Move every song of the source groups into this group and delete the source groups in one transaction.
+// @Description The source names are kept as aliases: songs added or updated under an alias are filed under this group,
+// @Description and the group filter of GET /songs matches aliases too. Groups whose live songs share a title are not merged.
+// @Tags groups
+// @Param id path int true "Target group ID"
+// @Param merge body service.GroupMergeRequest true "Groups to merge into the target"
+// @Success 200 {object} service.GroupMergeResult
+// @Failure 400 {object} gin.H{"error": "invalid merge: source_ids cannot be empty"}
+// @Failure 404 {object} gin.H{"error": "Group not found"}
+// @Failure 409 {object} gin.H{"error": "song already exists: the groups share 1 song titles; delete the extra songs before merging"}
+// @Failure 500 {object} gin.H{"error": "Could not merge groups"}
+// @Router /groups/{id}/merge [post]
+func (h *Handler) MergeGroups(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+	var request service.GroupMergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := h.GroupService.MergeGroups(c.Request.Context(), id, request)
+	if err != nil {
+		log.Printf("Error merging groups %v into group %d: %v", request.SourceIDs, id, err)
+		respondGroupError(c, err, "Could not merge groups")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func respondGroupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+	case errors.Is(err, service.ErrInvalidGroupName), errors.Is(err, service.ErrInvalidMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrGroupExists), errors.Is(err, repository.ErrGroupHasSongs), errors.Is(err, repository.ErrDuplicateSong):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondError(c, err, http.StatusInternalServerError, message)
+	}
+}
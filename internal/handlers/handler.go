@@ -1,34 +1,95 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"net/http"
 	"song-library/internal/service"
+	"song-library/pkg/idempotency"
 )
 
 type Handler struct {
-	SongService *service.SongService
+	SongService      *service.SongService
+	GroupService     *service.GroupService
+	AlbumService     *service.AlbumService
+	EnrichmentWorker *service.EnrichmentWorker
+	Imports          *service.ImportManager
+	Idempotency      *idempotency.Store
 }
 
-func NewHandler(songService *service.SongService) *Handler {
-	return &Handler{SongService: songService}
+func NewHandler(songService *service.SongService, groupService *service.GroupService, albumService *service.AlbumService, enrichmentWorker *service.EnrichmentWorker, imports *service.ImportManager, idempotencyStore *idempotency.Store) *Handler {
+	return &Handler{SongService: songService, GroupService: groupService, AlbumService: albumService, EnrichmentWorker: enrichmentWorker, Imports: imports, Idempotency: idempotencyStore}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.Default()
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/debug/breakers", h.GetBreakers)
+	router.GET("/enrichment/status", h.GetEnrichmentStatus)
+
+	imports := router.Group("/imports")
+	{
+		imports.GET("", h.ListImports)
+		imports.POST("", h.CreateImport)
+		imports.GET("/:id", h.GetImport)
+		imports.GET("/:id/errors", h.GetImportErrors)
+		imports.POST("/:id/commit", h.CommitImport)
+	}
+
+	groups := router.Group("/groups")
+	{
+		groups.GET("", h.GetGroups)
+		groups.POST("", h.AddGroup)
+		groups.GET("/:id", h.GetGroupByID)
+		groups.PATCH("/:id", h.RenameGroup)
+		groups.DELETE("/:id", h.DeleteGroup)
+		groups.GET("/:id/songs", h.GetGroupSongs)
+		groups.POST("/:id/merge", h.MergeGroups)
+	}
+
+	albums := router.Group("/albums")
+	{
+		albums.GET("", h.GetAlbums)
+		albums.POST("", h.AddAlbum)
+		albums.GET("/:id", h.GetAlbumByID)
+		albums.PATCH("/:id", h.PatchAlbum)
+		albums.DELETE("/:id", h.DeleteAlbum)
+		albums.GET("/:id/tracks", h.GetAlbumTracks)
+	}
 
 	songs := router.Group("/songs")
 	{
 		songs.GET("/", h.GetSongs)
-		songs.POST("/", h.AddSong)
+		songs.POST("/", h.idempotent, h.AddSong)
+		songs.POST("/bulk", h.BulkAddSongs)
+		songs.GET("/search", h.SearchSongs)
+		songs.GET("/export", h.ExportSongs)
+		songs.GET("/trash", h.GetTrash)
+		songs.DELETE("/trash/:id", h.PurgeSong)
 		songs.GET("/:id", h.GetSongByID)
 		songs.PUT("/:id", h.UpdateSong)
+		songs.PATCH("/:id", h.PatchSong)
 		songs.DELETE("/:id", h.DeleteSong)
+		songs.POST("/:id/restore", h.RestoreSong)
+		songs.GET("/:id/revisions", h.GetSongRevisions)
+		songs.GET("/:id/revisions/:rev", h.GetSongRevision)
+		songs.POST("/:id/revisions/:rev/restore", h.RollbackSong)
 		songs.GET("/:id/lyrics", h.GetSongLyrics)
 		songs.GET("/:id/lyrics/:range", h.GetSongLyricsByRange)
 	}
 
 	return router
 }
+
+// respondError writes the given status and message, except for requests whose
+// database work ran past its deadline, which are reported as 504.
+func respondError(c *gin.Context, err error, status int, message string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		return
+	}
+	c.JSON(status, gin.H{"error": message})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"song-library/pkg/idempotency"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes bounds the body read into memory to fingerprint
+	// a request.
+	maxIdempotentBodyBytes = 1 << 20
+)
+
+// responseRecorder copies what a handler writes so it can be replayed.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// idempotent makes a POST safe to retry. The response to a request carrying
+// an Idempotency-Key header is recorded, and a retry with the same key, path
+// and body gets it replayed instead of running the handler again. Server
+// errors are not recorded so the request can be retried. Requests without
+// the header are passed through.
+func (h *Handler) idempotent(c *gin.Context) {
+	key := c.GetHeader("Idempotency-Key")
+	if h.Idempotency == nil || key == "" {
+		c.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	fingerprint := c.Request.Method + " " + c.Request.URL.RequestURI() + " " + hex.EncodeToString(sum[:])
+
+	recorded, err := h.Idempotency.Begin(key, fingerprint)
+	switch {
+	case errors.Is(err, idempotency.ErrMismatch):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+		return
+	case errors.Is(err, idempotency.ErrInProgress):
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+		return
+	case recorded != nil:
+		for name, values := range recorded.Header {
+			c.Writer.Header()[name] = values
+		}
+		c.Header("Idempotent-Replayed", "true")
+		c.Status(recorded.Status)
+		c.Writer.Write(recorded.Body)
+		c.Abort()
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	completed := false
+	defer func() {
+		if !completed {
+			h.Idempotency.Release(key)
+		}
+	}()
+
+	c.Next()
+
+	if status := recorder.Status(); status < http.StatusInternalServerError {
+		h.Idempotency.Complete(key, idempotency.Response{
+			Status: status,
+			Header: recorder.Header().Clone(),
+			Body:   recorder.body.Bytes(),
+		})
+		completed = true
+	}
+}
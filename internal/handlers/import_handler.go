@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"song-library/internal/service"
+	"strings"
+	"unicode/utf8"
+)
+
+// @Summary Start a catalog import
+// @Description Upload a CSV or NDJSON file, either as the multipart field "file" or as the raw request body.
+// @Description CSV headers are matched to group, song, release_date, lyrics and link by name unless remapped
+// @Description with map[Header]=field. With dry_run=true the rows are only validated; commit the dry run later
+// @Description with POST /imports/{id}/commit. Progress is reported by GET /imports/{id}.
+// @Tags imports
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Param file formData file false "File to import"
+// @Param format query string false "csv or ndjson; inferred from the content type or file name when omitted"
+// @Param delimiter query string false "CSV field delimiter" default(,)
+// @Param dry_run query bool false "Only validate the rows" default(false)
+// @Param mode query string false "best_effort or all_or_nothing" default(best_effort)
+// @Param on_conflict query string false "error, ignore or update; rows duplicating a live song are reported, skipped or replace it" default(error)
+// @Success 202 {object} service.ImportJob
+// @Failure 400 {object} gin.H{"error": "invalid import: no column maps to song"}
+// @Failure 413 {object} gin.H{"error": "File too large"}
+// @Failure 503 {object} gin.H{"error": "import queue is full"}
+// @Router /imports [post]
+func (h *Handler) CreateImport(c *gin.Context) {
+	opts := service.ImportOptions{
+		Mapping: c.QueryMap("map"),
+		DryRun:  c.Query("dry_run") == "true",
+		Mode:    service.BulkMode(c.DefaultQuery("mode", string(service.BulkBestEffort))),
+	}
+	if opts.Mode != service.BulkBestEffort && opts.Mode != service.BulkAllOrNothing {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode, expected best_effort or all_or_nothing"})
+		return
+	}
+	var ok bool
+	if opts.OnConflict, ok = conflictPolicy(c); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid on_conflict, expected error, ignore or update"})
+		return
+	}
+	if delimiter := c.Query("delimiter"); delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delimiter"})
+			return
+		}
+		opts.Delimiter = r
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.Imports.MaxUploadBytes)
+	data, filename, err := readUpload(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read upload"})
+		return
+	}
+
+	opts.Format = importFormat(c.Query("format"), c.ContentType(), filename)
+	if opts.Format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not determine format, pass format=csv or format=ndjson"})
+		return
+	}
+
+	job, err := h.Imports.CreateImport(data, opts)
+	if err != nil {
+		log.Printf("Error creating import: %v", err)
+		switch {
+		case errors.Is(err, service.ErrInvalidImport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrImportQueueFull):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create import"})
+		}
+		return
+	}
+
+	c.Header("Location", "/imports/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary List imports
+// @Description List the tracked import jobs, newest first
+// @Tags imports
+// @Success 200 {array} service.ImportJob
+// @Router /imports [get]
+func (h *Handler) ListImports(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Imports.ListImports())
+}
+
+// @Summary Get an import
+// @Description Get the status and progress of an import job
+// @Tags imports
+// @Param id path string true "Import ID"
+// @Success 200 {object} service.ImportJob
+// @Failure 404 {object} gin.H{"error": "import not found"}
+// @Router /imports/{id} [get]
+func (h *Handler) GetImport(c *gin.Context) {
+	job, err := h.Imports.GetImport(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// @Summary Download import errors
+// @Description Download the rejected rows of an import as CSV with the columns line, reason and record
+// @Tags imports
+// @Produce text/csv
+// @Param id path string true "Import ID"
+// @Success 200 {file} file
+// @Failure 404 {object} gin.H{"error": "import not found"}
+// @Router /imports/{id}/errors [get]
+func (h *Handler) GetImportErrors(c *gin.Context) {
+	id := c.Param("id")
+	file, err := h.Imports.ErrorFile(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="import-`+id+`-errors.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", file)
+}
+
+// @Summary Commit a dry run
+// @Description Run a finished dry-run import for real
+// @Tags imports
+// @Param id path string true "Import ID"
+// @Success 202 {object} service.ImportJob
+// @Failure 404 {object} gin.H{"error": "import not found"}
+// @Failure 409 {object} gin.H{"error": "import is not a finished dry run"}
+// @Router /imports/{id}/commit [post]
+func (h *Handler) CommitImport(c *gin.Context) {
+	job, err := h.Imports.CommitImport(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrImportNotReady):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// readUpload returns the uploaded file and its name, taken from the "file"
+// field of a multipart form or else from the raw request body.
+func readUpload(c *gin.Context) ([]byte, string, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		data, err := io.ReadAll(c.Request.Body)
+		return data, "", err
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := readFormFile(header)
+	return data, header.Filename, err
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// importFormat picks the upload format from the format parameter, the
+// content type or the file extension, in that order.
+func importFormat(format, contentType, filename string) service.ImportFormat {
+	switch strings.ToLower(format) {
+	case "csv":
+		return service.ImportCSV
+	case "ndjson", "jsonl":
+		return service.ImportNDJSON
+	case "":
+	default:
+		return ""
+	}
+
+	switch contentType {
+	case "text/csv":
+		return service.ImportCSV
+	case "application/x-ndjson", "application/ndjson":
+		return service.ImportNDJSON
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return service.ImportCSV
+	case ".ndjson", ".jsonl":
+		return service.ImportNDJSON
+	}
+	return ""
+}
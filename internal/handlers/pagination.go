@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/url"
+	"song-library/internal/service"
+	"strconv"
+	"strings"
+)
+
+// envelopeMediaType lets clients opt into the paginated envelope through the
+// Accept header instead of the envelope query flag.
+const envelopeMediaType = "application/vnd.song-library.v2+json"
+
+type pageLink struct {
+	rel   string
+	query map[string]string
+}
+
+func wantsEnvelope(c *gin.Context) bool {
+	if envelope, err := strconv.ParseBool(c.Query("envelope")); err == nil && envelope {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), envelopeMediaType)
+}
+
+// setLinkHeader emits an RFC 8288 Link header whose targets are the current
+// request URL with the given query parameters replaced.
+func setLinkHeader(c *gin.Context, links []pageLink) {
+	if len(links) == 0 {
+		return
+	}
+
+	values := make([]string, 0, len(links))
+	for _, link := range links {
+		u := *c.Request.URL
+		query := u.Query()
+		for key, value := range link.query {
+			query.Set(key, value)
+		}
+		u.RawQuery = query.Encode()
+		values = append(values, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), link.rel))
+	}
+	c.Header("Link", strings.Join(values, ", "))
+}
+
+func offsetLinks(page, totalPages int) []pageLink {
+	last := totalPages
+	if last < 1 {
+		last = 1
+	}
+
+	links := []pageLink{{rel: "first", query: map[string]string{"page": "1"}}}
+	if page > 1 {
+		links = append(links, pageLink{rel: "prev", query: map[string]string{"page": strconv.Itoa(min(page-1, last))}})
+	}
+	if page < last {
+		links = append(links, pageLink{rel: "next", query: map[string]string{"page": strconv.Itoa(page + 1)}})
+	}
+	return append(links, pageLink{rel: "last", query: map[string]string{"page": strconv.Itoa(last)}})
+}
+
+// listLinks is offsetLinks for a page of unknown total: there is no last
+// link and next is offered whenever the page is full.
+func listLinks(page int, full bool) []pageLink {
+	links := []pageLink{{rel: "first", query: map[string]string{"page": "1"}}}
+	if page > 1 {
+		links = append(links, pageLink{rel: "prev", query: map[string]string{"page": strconv.Itoa(page - 1)}})
+	}
+	if full {
+		links = append(links, pageLink{rel: "next", query: map[string]string{"page": strconv.Itoa(page + 1)}})
+	}
+	return links
+}
+
+func cursorLinks(nextCursor, prevCursor string) []pageLink {
+	var links []pageLink
+	if prevCursor != "" {
+		links = append(links, pageLink{rel: "prev", query: map[string]string{"cursor": prevCursor}})
+	}
+	if nextCursor != "" {
+		links = append(links, pageLink{rel: "next", query: map[string]string{"cursor": nextCursor}})
+	}
+	return links
+}
+
+// didYouMeanHeader renders a suggestion as the query parameters to retry
+// with, for callers of the bare array that have no envelope to carry it.
+func didYouMeanHeader(suggestion *service.Suggestion) string {
+	query := url.Values{}
+	if suggestion.Group != "" {
+		query.Set("group", suggestion.Group)
+	}
+	if suggestion.Song != "" {
+		query.Set("song", suggestion.Song)
+	}
+	return query.Encode()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/repository"
+	"strconv"
+)
+
+// @Summary List song revisions
+// @Description List every recorded revision of a song, newest first
+// @Tags songs
+// @Param id path int true "Song ID"
+// @Success 200 {object} gin.H{"revisions": []models.SongRevision}
+// @Failure 400 {object} gin.H{"error": "Invalid song ID"}
+// @Failure 404 {object} gin.H{"error": "Song not found"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch revisions"}
+// @Router /songs/{id}/revisions [get]
+func (h *Handler) GetSongRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+		return
+	}
+
+	revisions, err := h.SongService.GetSongRevisions(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error fetching revisions of song with ID %d: %v", id, err)
+		respondRevisionError(c, err, "Could not fetch revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// @Summary Get a song revision
+// @Description Get one revision of a song with a line-level diff of its lyrics against the previous revision
+// @Tags songs
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} service.SongRevisionDetail
+// @Failure 400 {object} gin.H{"error": "Invalid revision number"}
+// @Failure 404 {object} gin.H{"error": "Revision not found"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch revision"}
+// @Router /songs/{id}/revisions/{rev} [get]
+func (h *Handler) GetSongRevision(c *gin.Context) {
+	id, rev, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := h.SongService.GetSongRevision(c.Request.Context(), id, rev)
+	if err != nil {
+		log.Printf("Error fetching revision %d of song with ID %d: %v", rev, id, err)
+		respondRevisionError(c, err, "Could not fetch revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// @Summary Roll back a song
+// @Description Restore a song to the state captured in one of its revisions; the rollback is recorded as a new revision
+// @Tags songs
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} gin.H{"message": "Song restored to revision"}
+// @Failure 400 {object} gin.H{"error": "Invalid revision number"}
+// @Failure 404 {object} gin.H{"error": "Revision not found"}
+// @Failure 409 {object} gin.H{"error": "Song already exists", "id": int, "location": "/songs/1"}
+// @Failure 500 {object} gin.H{"error": "Could not restore revision"}
+// @Router /songs/{id}/revisions/{rev}/restore [post]
+func (h *Handler) RollbackSong(c *gin.Context) {
+	id, rev, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SongService.RollbackSong(c.Request.Context(), id, rev); err != nil {
+		log.Printf("Error restoring song with ID %d to revision %d: %v", id, rev, err)
+		respondRevisionError(c, err, "Could not restore revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Song restored to revision " + strconv.Itoa(rev)})
+}
+
+func revisionParams(c *gin.Context) (id, rev int, ok bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+		return 0, 0, false
+	}
+	rev, err = strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return 0, 0, false
+	}
+	return id, rev, true
+}
+
+func respondRevisionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+	case errors.Is(err, repository.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+	case errors.Is(err, repository.ErrDuplicateSong):
+		respondDuplicateSong(c, err)
+	default:
+		respondError(c, err, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+	"strings"
+)
+
+// @Summary Search songs
+// @Description Full-text search over song titles and lyrics, ranked by relevance.
+// @Description Supports bare words, "quoted phrases" and prefix* terms; each result carries a highlighted snippet of the matching verse.
+// @Tags songs
+// @Param q query string true "Search query"
+// @Param lang query string false "Text search configuration (simple, english, russian)" default(simple)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Limit per page" default(10)
+// @Success 200 {object} service.SongSearchPage
+// @Failure 400 {object} gin.H{"error": "Invalid search query"}
+// @Failure 500 {object} gin.H{"error": "Could not search songs"}
+// @Failure 501 {object} gin.H{"error": "Search is not available"}
+// @Router /songs/search [get]
+func (h *Handler) SearchSongs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+
+	query := repository.SearchQuery{
+		Text:     c.Query("q"),
+		Language: c.DefaultQuery("lang", "simple"),
+		Page:     page,
+		Limit:    limit,
+	}
+	results, err := h.SongService.SearchSongs(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("Error searching songs: %v", err)
+		switch {
+		case errors.Is(err, repository.ErrInvalidSearchQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search query"})
+		case errors.Is(err, repository.ErrUnsupportedLanguage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language, expected one of: " +
+				strings.Join(repository.SearchLanguages, ", ")})
+		case errors.Is(err, service.ErrSearchUnsupported):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Search is not available"})
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not search songs")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"song-library/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseSongFilter reads and validates the GET /songs filter parameters.
+func parseSongFilter(c *gin.Context) (repository.SongFilter, error) {
+	filter := repository.SongFilter{
+		Group:    c.DefaultQuery("group", ""),
+		Song:     c.DefaultQuery("song", ""),
+		LinkHost: strings.TrimSpace(c.Query("link_host")),
+	}
+
+	var err error
+	if filter.ReleaseFrom, err = dateParam(c, "release_from"); err != nil {
+		return filter, err
+	}
+	if filter.ReleaseTo, err = dateParam(c, "release_to"); err != nil {
+		return filter, err
+	}
+	if filter.ReleaseFrom != nil && filter.ReleaseTo != nil && filter.ReleaseFrom.After(*filter.ReleaseTo) {
+		return filter, fmt.Errorf("release_from must not be after release_to")
+	}
+
+	if value, ok := c.GetQuery("year"); ok {
+		if filter.Year, err = strconv.Atoi(value); err != nil || filter.Year < 1 || filter.Year > 9999 {
+			return filter, fmt.Errorf("invalid year, expected a number between 1 and 9999")
+		}
+	}
+	if value, ok := c.GetQuery("decade"); ok {
+		if filter.Decade, err = strconv.Atoi(value); err != nil || filter.Decade < 10 || filter.Decade > 9990 || filter.Decade%10 != 0 {
+			return filter, fmt.Errorf("invalid decade, expected the first year of a decade such as 1990")
+		}
+	}
+
+	if filter.HasLyrics, err = boolParam(c, "has_lyrics"); err != nil {
+		return filter, err
+	}
+	if filter.HasLink, err = boolParam(c, "has_link"); err != nil {
+		return filter, err
+	}
+	if strings.ContainsAny(filter.LinkHost, "/:?#@ ") {
+		return filter, fmt.Errorf("invalid link_host, expected a host name such as example.com")
+	}
+
+	if filter.CreatedAfter, err = timeParam(c, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedAfter, err = timeParam(c, "updated_after"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func dateParam(c *gin.Context, name string) (*time.Time, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected YYYY-MM-DD", name)
+	}
+	return &date, nil
+}
+
+// timeParam accepts an RFC 3339 timestamp or a plain YYYY-MM-DD date.
+func timeParam(c *gin.Context, name string) (*time.Time, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s, expected an RFC 3339 timestamp or YYYY-MM-DD", name)
+}
+
+func boolParam(c *gin.Context, name string) (*bool, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected true or false", name)
+	}
+	return &b, nil
+}
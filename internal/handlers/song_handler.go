@@ -1,31 +1,60 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
+	"song-library/internal/models"
+	"song-library/internal/repository"
 	"song-library/internal/service"
 	"strconv"
 	"strings"
 )
 
 // @Summary Get all songs
-// @Description Get a list of songs with pagination and optional filters
+// @Description Get a list of songs with pagination and optional filters.
+// @Description Passing cursor (empty for the first page) switches to stable keyset pagination.
+// @Description Pass envelope=true or Accept: application/vnd.song-library.v2+json to receive a paginated envelope.
+// @Description Pagination links are returned in an RFC 8288 Link header.
+// @Description match=fuzzy tolerates typos and orders by similarity unless sort is given; the other filters still apply.
+// @Description A filtered result that matched nothing carries a did_you_mean suggestion, in the envelope or, for a bare array,
+// @Description as a Did-You-Mean header holding the suggested group and song query parameters.
 // @Tags songs
 // @Param group query string false "Group filter"
 // @Param song query string false "Song filter"
+// @Param release_from query string false "Released on or after (YYYY-MM-DD)"
+// @Param release_to query string false "Released on or before (YYYY-MM-DD)"
+// @Param year query int false "Release year"
+// @Param decade query int false "Release decade, given as its first year (e.g. 1990)"
+// @Param has_lyrics query bool false "Only songs with (true) or without (false) lyrics"
+// @Param has_link query bool false "Only songs with (true) or without (false) a link"
+// @Param link_host query string false "Link host or parent domain (e.g. youtube.com)"
+// @Param created_after query string false "Created after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_after query string false "Updated after (RFC 3339 or YYYY-MM-DD)"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Limit per page" default(10)
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor"
+// @Param envelope query bool false "Wrap the result in a pagination envelope"
+// @Param sort query string false "Comma separated sort keys (id, group, song, release_date, created_at, updated_at); prefix with - for descending" default(id)
+// @Param match query string false "substring (ILIKE) or fuzzy (trigram similarity)" default(substring)
+// @Param threshold query number false "Minimum similarity for fuzzy matches, in (0, 1]"
 // @Success 200 {array} service.Song
+// @Header 200 {string} Did-You-Mean "group=Imagine+Dragons"
+// @Success 200 {object} service.SongPage
+// @Success 200 {object} service.ScoredSongPage
+// @Success 200 {object} service.SongCursorPage
 // @Failure 400 {object} gin.H{"error": "Invalid page number"}
+// @Failure 400 {object} gin.H{"error": "invalid release_from, expected YYYY-MM-DD"}
+// @Failure 400 {object} gin.H{"error": "Invalid cursor"}
+// @Failure 400 {object} gin.H{"error": "invalid sort", "allowed_fields": []string}
 // @Failure 500 {object} gin.H{"error": "Could not fetch songs"}
+// @Failure 504 {object} gin.H{"error": "Request timed out"}
 // @Router /songs [get]
 func (h *Handler) GetSongs(c *gin.Context) {
-	group := c.DefaultQuery("group", "")
-	song := c.DefaultQuery("song", "")
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+	filter, err := parseSongFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -34,23 +63,141 @@ func (h *Handler) GetSongs(c *gin.Context) {
 		return
 	}
 
-	songs, err := h.SongService.GetSongs(group, song, page, limit)
+	sort, err := repository.ParseSongSort(c.DefaultQuery("sort", "id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          err.Error(),
+			"allowed_fields": repository.SortableFields,
+		})
+		return
+	}
+
+	switch c.DefaultQuery("match", "substring") {
+	case "substring":
+	case "fuzzy":
+		if _, ok := c.GetQuery("sort"); !ok {
+			sort = nil
+		}
+		h.getFuzzySongs(c, filter, sort, limit)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match mode, expected substring or fuzzy"})
+		return
+	}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		h.getSongsByCursor(c, filter, sort, cursor, limit)
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+
+	if !wantsEnvelope(c) {
+		// The bare array has no total, so it is not counted.
+		songs, err := h.SongService.GetSongList(c.Request.Context(), filter, sort, page, limit)
+		if err != nil {
+			log.Printf("Error fetching songs: %v", err)
+			respondError(c, err, http.StatusInternalServerError, "Could not fetch songs")
+			return
+		}
+		setLinkHeader(c, listLinks(page, len(songs.Items) == limit))
+		if songs.DidYouMean != nil {
+			c.Header("Did-You-Mean", didYouMeanHeader(songs.DidYouMean))
+		}
+		c.JSON(http.StatusOK, songs.Items)
+		return
+	}
+
+	songs, err := h.SongService.GetSongs(c.Request.Context(), filter, sort, page, limit)
 	if err != nil {
 		log.Printf("Error fetching songs: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch songs"})
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch songs")
 		return
 	}
 
+	setLinkHeader(c, offsetLinks(page, songs.TotalPages))
+	if songs.Items == nil {
+		songs.Items = []models.Song{}
+	}
 	c.JSON(http.StatusOK, songs)
 }
 
+// getFuzzySongs ranks songs by similarity, or orders them by sort when one
+// is given; the other filters narrow the matches as usual.
+func (h *Handler) getFuzzySongs(c *gin.Context, filter repository.SongFilter, sort repository.SongSort, limit int) {
+	if filter.Group == "" && filter.Song == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuzzy match requires a group or song filter"})
+		return
+	}
+	if _, ok := c.GetQuery("cursor"); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor pagination is not supported with fuzzy match"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	threshold := 0.0
+	if value, ok := c.GetQuery("threshold"); ok {
+		threshold, err = strconv.ParseFloat(value, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold, expected a number in (0, 1]"})
+			return
+		}
+	}
+
+	songs, err := h.SongService.FuzzySongs(c.Request.Context(), repository.FuzzyQuery{
+		Group:     filter.Group,
+		Song:      filter.Song,
+		Threshold: threshold,
+		Filter:    filter,
+		Sort:      sort,
+		Page:      page,
+		Limit:     limit,
+	})
+	if err != nil {
+		log.Printf("Error fuzzy matching songs: %v", err)
+		if errors.Is(err, service.ErrFuzzyUnsupported) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "Fuzzy matching is not available"})
+			return
+		}
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch songs")
+		return
+	}
+
+	c.JSON(http.StatusOK, songs)
+}
+
+func (h *Handler) getSongsByCursor(c *gin.Context, filter repository.SongFilter, sort repository.SongSort, cursor string, limit int) {
+	page, err := h.SongService.GetSongsByCursor(c.Request.Context(), filter, sort, cursor, limit)
+	if err != nil {
+		log.Printf("Error fetching songs by cursor: %v", err)
+		if errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch songs")
+		return
+	}
+
+	setLinkHeader(c, cursorLinks(page.NextCursor, page.PrevCursor))
+	c.JSON(http.StatusOK, page)
+}
+
 // @Summary Get a song by ID
 // @Description Get the details of a song by its ID
 // @Tags songs
 // @Param id path int true "Song ID"
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} gin.H{"song": service.Song}
+// @Success 304 "Song has not changed"
 // @Failure 400 {object} gin.H{"error": "Invalid song ID"}
 // @Failure 404 {object} gin.H{"error": "Song not found"}
 // @Router /songs/{id} [get]
@@ -70,10 +217,15 @@ func (h *Handler) GetSongByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page size"})
 		return
 	}
-	song, err := h.SongService.GetSongByID(strconv.Itoa(id))
+	song, err := h.SongService.GetSongByID(c.Request.Context(), strconv.Itoa(id))
 	if err != nil {
 		log.Printf("Error fetching song with ID %d: %v", id, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		respondError(c, err, http.StatusNotFound, "Song not found")
+		return
+	}
+	c.Header("ETag", songETag(song.Version))
+	if notModified(c, song.Version) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"song": song})
@@ -95,9 +247,9 @@ func (h *Handler) GetSongLyrics(c *gin.Context) {
 		return
 	}
 
-	lyrics, err := h.SongService.GetSongLyricsWithRange(id, 1, 0)
+	lyrics, err := h.SongService.GetSongLyricsWithRange(c.Request.Context(), id, 1, 0)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch lyrics"})
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch lyrics")
 		return
 	}
 
@@ -150,9 +302,9 @@ func (h *Handler) GetSongLyricsByRange(c *gin.Context) {
 		end = start
 	}
 
-	lyrics, err := h.SongService.GetSongLyricsWithRange(id, start, end)
+	lyrics, err := h.SongService.GetSongLyricsWithRange(c.Request.Context(), id, start, end)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch lyrics"})
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch lyrics")
 		return
 	}
 
@@ -160,14 +312,33 @@ func (h *Handler) GetSongLyricsByRange(c *gin.Context) {
 }
 
 // @Summary Add a new song
-// @Description Add a new song to the library
+// @Description Add a new song to the library and return it as stored. A group holds at most one live song per title, compared
+// @Description case-insensitively. By default a duplicate is refused with 409 and the existing song's id and location;
+// @Description on_conflict=ignore keeps the existing song and on_conflict=update replaces it, both answering 200 with that song
+// @Description and a Content-Location header.
+// @Description A request sent with an Idempotency-Key is answered from the recorded response when retried with the same key and body.
 // @Tags songs
 // @Param song body service.SongRequest true "New song details"
-// @Success 201 {object} gin.H{"message": "Song created successfully"}
+// @Param on_conflict query string false "error, ignore or update" default(error)
+// @Param Idempotency-Key header string false "Client-generated key that makes retries of this request safe"
+// @Success 201 {object} models.Song
+// @Header 201 {string} Location "/songs/1"
+// @Success 200 {object} models.Song
+// @Header 200 {string} Content-Location "/songs/1"
 // @Failure 400 {object} gin.H{"error": "Invalid request body"}
+// @Failure 409 {object} gin.H{"error": "Song already exists", "id": int, "location": "/songs/1"}
+// @Failure 422 {object} gin.H{"error": "Idempotency-Key was already used for a different request"}
+// @Failure 413 {object} gin.H{"error": "Request body too large"}
+// @Failure 502 {object} gin.H{"error": "Could not fetch song details"}
 // @Failure 500 {object} gin.H{"error": "Could not add song"}
 // @Router /songs [post]
 func (h *Handler) AddSong(c *gin.Context) {
+	onConflict, ok := conflictPolicy(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid on_conflict, expected error, ignore or update"})
+		return
+	}
+
 	var songRequest service.SongRequest
 
 	if err := c.ShouldBindJSON(&songRequest); err != nil {
@@ -175,13 +346,31 @@ func (h *Handler) AddSong(c *gin.Context) {
 		return
 	}
 
-	if err := h.SongService.AddSong(songRequest); err != nil {
+	result, err := h.SongService.AddSong(c.Request.Context(), songRequest, onConflict)
+	if err != nil {
 		log.Printf("Error adding song: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add song"})
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrEnrichment):
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Could not fetch song details"})
+		case errors.Is(err, repository.ErrDuplicateSong):
+			respondDuplicateSong(c, err)
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not add song")
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Song created successfully"})
+	location := "/songs/" + strconv.Itoa(result.Song.ID)
+	c.Header("ETag", songETag(result.Song.Version))
+	if result.Status != service.SongCreated {
+		c.Header("Content-Location", location)
+		c.JSON(http.StatusOK, result.Song)
+		return
+	}
+	c.Header("Location", location)
+	c.JSON(http.StatusCreated, result.Song)
 }
 
 // @Summary Update a song
@@ -189,8 +378,12 @@ func (h *Handler) AddSong(c *gin.Context) {
 // @Tags songs
 // @Param id path int true "Song ID"
 // @Param song body service.SongRequest true "Updated song details"
+// @Param If-Match header string false "ETag, or comma-separated ETags, the update is based on"
 // @Success 200 {object} gin.H{"message": "Song updated successfully"}
 // @Failure 400 {object} gin.H{"error": "Invalid song ID"}
+// @Failure 404 {object} gin.H{"error": "Song not found"}
+// @Failure 409 {object} gin.H{"error": "Song already exists", "id": int, "location": "/songs/1"}
+// @Failure 412 {object} gin.H{"error": "Song has been modified"}
 // @Failure 500 {object} gin.H{"error": "Could not update song"}
 // @Router /songs/{id} [put]
 func (h *Handler) UpdateSong(c *gin.Context) {
@@ -200,27 +393,117 @@ func (h *Handler) UpdateSong(c *gin.Context) {
 		return
 	}
 
+	ifVersion, conditional, err := h.ifMatchVersion(c, id)
+	if err != nil {
+		respondPrecondition(c, err, "Could not update song")
+		return
+	}
+
 	var songRequest service.SongRequest
 	if err := c.ShouldBindJSON(&songRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	if err := h.SongService.UpdateSong(id, songRequest); err != nil {
+	if err := h.SongService.UpdateSong(c.Request.Context(), id, songRequest, ifVersion); err != nil {
 		log.Printf("Error updating song with ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update song"})
+		switch {
+		case errors.Is(err, service.ErrValidation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrVersionMismatch), conditional && errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Song has been modified"})
+		case errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		case errors.Is(err, repository.ErrDuplicateSong):
+			respondDuplicateSong(c, err)
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not update song")
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Song updated successfully"})
 }
 
+// @Summary Partially update a song
+// @Description Apply a JSON Merge Patch (application/merge-patch+json, RFC 7396) or a JSON Patch
+// @Description (application/json-patch+json, RFC 6902) to a song. Only the fields that change are written.
+// @Tags songs
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Param id path int true "Song ID"
+// @Param patch body object true "Merge patch document or array of JSON Patch operations"
+// @Param If-Match header string false "ETag, or comma-separated ETags, the patch is based on"
+// @Success 200 {object} gin.H{"song": service.Song}
+// @Failure 400 {object} gin.H{"error": "invalid patch"}
+// @Failure 404 {object} gin.H{"error": "Song not found"}
+// @Failure 409 {object} gin.H{"error": "patch test failed"}
+// @Failure 409 {object} gin.H{"error": "Song already exists", "id": int, "location": "/songs/1"}
+// @Failure 412 {object} gin.H{"error": "Song has been modified"}
+// @Failure 415 {object} gin.H{"error": "Unsupported patch format"}
+// @Failure 500 {object} gin.H{"error": "Could not update song"}
+// @Router /songs/{id} [patch]
+func (h *Handler) PatchSong(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != service.MergePatchContentType && contentType != service.JSONPatchContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":     "Unsupported patch format",
+			"supported": []string{service.MergePatchContentType, service.JSONPatchContentType},
+		})
+		return
+	}
+
+	ifVersion, conditional, err := h.ifMatchVersion(c, id)
+	if err != nil {
+		respondPrecondition(c, err, "Could not update song")
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	song, err := h.SongService.PatchSong(c.Request.Context(), id, contentType, patch, ifVersion)
+	if err != nil {
+		log.Printf("Error patching song with ID %d: %v", id, err)
+		switch {
+		case errors.Is(err, service.ErrInvalidPatch), errors.Is(err, service.ErrValidation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPatchTestFailed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrVersionMismatch), conditional && errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Song has been modified"})
+		case errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		case errors.Is(err, repository.ErrDuplicateSong):
+			respondDuplicateSong(c, err)
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not update song")
+		}
+		return
+	}
+
+	c.Header("ETag", songETag(song.Version))
+	c.JSON(http.StatusOK, gin.H{"song": song})
+}
+
 // @Summary Delete a song
-// @Description Delete a song by its ID
+// @Description Move a song to the trash; it can be restored until it is purged
 // @Tags songs
 // @Param id path int true "Song ID"
+// @Param If-Match header string false "ETag, or comma-separated ETags, the delete is based on"
 // @Success 204 {object} gin.H{"message": "Song deleted successfully"}
 // @Failure 400 {object} gin.H{"error": "Invalid song ID"}
+// @Failure 404 {object} gin.H{"error": "Song not found"}
+// @Failure 412 {object} gin.H{"error": "Song has been modified"}
 // @Failure 500 {object} gin.H{"error": "Could not delete song"}
 // @Router /songs/{id} [delete]
 func (h *Handler) DeleteSong(c *gin.Context) {
@@ -230,12 +513,45 @@ func (h *Handler) DeleteSong(c *gin.Context) {
 		return
 	}
 
-	err = h.SongService.DeleteSong(id)
+	ifVersion, conditional, err := h.ifMatchVersion(c, id)
+	if err != nil {
+		respondPrecondition(c, err, "Could not delete song")
+		return
+	}
+
+	err = h.SongService.DeleteSong(c.Request.Context(), id, ifVersion)
 	if err != nil {
 		log.Printf("Error deleting song with ID %d: %v", id, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete song"})
+		switch {
+		case errors.Is(err, repository.ErrVersionMismatch), conditional && errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Song has been modified"})
+		case errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found"})
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not delete song")
+		}
 		return
 	}
 
 	c.JSON(http.StatusNoContent, gin.H{"message": "Song deleted successfully"})
 }
+
+// conflictPolicy reads the on_conflict query parameter, which defaults to
+// service.ConflictError.
+func conflictPolicy(c *gin.Context) (service.ConflictPolicy, bool) {
+	policy := service.ConflictPolicy(c.DefaultQuery("on_conflict", string(service.ConflictError)))
+	return policy, policy.Valid()
+}
+
+// respondDuplicateSong answers 409 with the ID and location of the song that
+// err reports as already holding the group and title.
+func respondDuplicateSong(c *gin.Context, err error) {
+	var duplicate *repository.DuplicateSongError
+	if !errors.As(err, &duplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	location := "/songs/" + strconv.Itoa(duplicate.ExistingID)
+	c.Header("Location", location)
+	c.JSON(http.StatusConflict, gin.H{"error": "Song already exists", "id": duplicate.ExistingID, "location": location})
+}
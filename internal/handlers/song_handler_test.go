@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingStore records how often the songs are counted.
+type countingStore struct {
+	*repository.MemorySongRepository
+	counts int
+}
+
+func (s *countingStore) CountSongs(ctx context.Context, filter repository.SongFilter) (int, error) {
+	s.counts++
+	return s.MemorySongRepository.CountSongs(ctx, filter)
+}
+
+func TestGetSongsCountsOnlyEnvelopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &countingStore{MemorySongRepository: repository.NewMemorySongRepository()}
+	release := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+	for _, title := range []string{"Hysteria", "Time Is Running Out", "Stockholm Syndrome"} {
+		if _, err := store.AddSong(context.Background(), models.Song{Group: "Muse", Song: title, ReleaseDate: &release}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := (&Handler{SongService: service.NewSongService(store, service.Config{CursorSecret: "test"})}).InitRoutes()
+
+	cases := []struct {
+		name       string
+		query      string
+		wantCounts int
+		wantItems  int
+		wantLink   string
+	}{
+		{name: "bare array", query: "?limit=2", wantItems: 2, wantLink: `rel="next"`},
+		{name: "bare array, last page", query: "?limit=2&page=2", wantItems: 1, wantLink: `rel="prev"`},
+		{name: "envelope", query: "?limit=2&envelope=true", wantCounts: 1, wantItems: 2, wantLink: `rel="last"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.counts = 0
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/songs/"+tc.query, nil))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("got %d %s", recorder.Code, recorder.Body)
+			}
+			if store.counts != tc.wantCounts {
+				t.Errorf("CountSongs called %d times, want %d", store.counts, tc.wantCounts)
+			}
+
+			var page struct {
+				Items []models.Song `json:"items"`
+			}
+			var target any = &page
+			if tc.wantCounts == 0 {
+				target = &page.Items
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), target); err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Items) != tc.wantItems {
+				t.Errorf("got %d songs, want %d", len(page.Items), tc.wantItems)
+			}
+			if link := recorder.Header().Get("Link"); !strings.Contains(link, tc.wantLink) {
+				t.Errorf("Link %q does not contain %s", link, tc.wantLink)
+			}
+		})
+	}
+}
+
+func TestPatchSongStatuses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        int
+	}{
+		{name: "merge patch", contentType: service.MergePatchContentType, patch: `{"lyrics": "Thunder"}`, want: http.StatusOK},
+		{name: "passing test", contentType: service.JSONPatchContentType, patch: `[{"op": "test", "path": "/release_date", "value": "2003-12-01"}]`, want: http.StatusOK},
+		{name: "failing test", contentType: service.JSONPatchContentType, patch: `[{"op": "test", "path": "/lyrics", "value": "Thunder"}]`, want: http.StatusConflict},
+		{name: "unknown member", contentType: service.MergePatchContentType, patch: `{"genre": "rock"}`, want: http.StatusBadRequest},
+		{name: "plain json", contentType: "application/json", patch: `{"lyrics": "Thunder"}`, want: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := repository.NewMemorySongRepository()
+			release := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+			song, err := store.AddSong(context.Background(), models.Song{Group: "Muse", Song: "Hysteria", ReleaseDate: &release, Lyrics: "It's bugging me"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			router := (&Handler{SongService: service.NewSongService(store, service.Config{CursorSecret: "test"})}).InitRoutes()
+
+			req := httptest.NewRequest(http.MethodPatch, "/songs/"+strconv.Itoa(song.ID), strings.NewReader(tc.patch))
+			req.Header.Set("Content-Type", tc.contentType)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			if recorder.Code != tc.want {
+				t.Errorf("got %d %s, want %d", recorder.Code, recorder.Body, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSongsRejectsUnknownSort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := (&Handler{SongService: service.NewSongService(repository.NewMemorySongRepository(), service.Config{CursorSecret: "test"})}).InitRoutes()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/songs/?sort=-genre", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("got %d %s, want 400", recorder.Code, recorder.Body)
+	}
+	var body struct {
+		AllowedFields []string `json:"allowed_fields"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(body.AllowedFields, ",") != strings.Join(repository.SortableFields, ",") {
+		t.Errorf("allowed_fields = %q, want %q", body.AllowedFields, repository.SortableFields)
+	}
+}
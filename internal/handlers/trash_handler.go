@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"strconv"
+)
+
+// @Summary List deleted songs
+// @Description List songs in the trash. Accepts the same filters, sort and paging as GET /songs and always returns a paginated envelope.
+// @Tags songs
+// @Param group query string false "Group filter"
+// @Param song query string false "Song filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Limit per page" default(10)
+// @Param sort query string false "Comma separated sort keys; prefix with - for descending" default(id)
+// @Success 200 {object} service.SongPage
+// @Failure 400 {object} gin.H{"error": "Invalid page number"}
+// @Failure 500 {object} gin.H{"error": "Could not fetch trash"}
+// @Failure 504 {object} gin.H{"error": "Request timed out"}
+// @Router /songs/trash [get]
+func (h *Handler) GetTrash(c *gin.Context) {
+	filter, err := parseSongFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit number"})
+		return
+	}
+	sort, err := repository.ParseSongSort(c.DefaultQuery("sort", "id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          err.Error(),
+			"allowed_fields": repository.SortableFields,
+		})
+		return
+	}
+
+	songs, err := h.SongService.GetTrash(c.Request.Context(), filter, sort, page, limit)
+	if err != nil {
+		log.Printf("Error fetching trash: %v", err)
+		respondError(c, err, http.StatusInternalServerError, "Could not fetch trash")
+		return
+	}
+
+	setLinkHeader(c, offsetLinks(page, songs.TotalPages))
+	if songs.Items == nil {
+		songs.Items = []models.Song{}
+	}
+	c.JSON(http.StatusOK, songs)
+}
+
+// @Summary Restore a deleted song
+// @Description Move a song out of the trash
+// @Tags songs
+// @Param id path int true "Song ID"
+// @Success 200 {object} gin.H{"message": "Song restored successfully"}
+// @Failure 400 {object} gin.H{"error": "Invalid song ID"}
+// @Failure 404 {object} gin.H{"error": "Song not found in trash"}
+// @Failure 409 {object} gin.H{"error": "Song already exists", "id": int, "location": "/songs/1"}
+// @Failure 500 {object} gin.H{"error": "Could not restore song"}
+// @Router /songs/{id}/restore [post]
+func (h *Handler) RestoreSong(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+		return
+	}
+
+	if err := h.SongService.RestoreSong(c.Request.Context(), id); err != nil {
+		log.Printf("Error restoring song with ID %d: %v", id, err)
+		switch {
+		case errors.Is(err, repository.ErrSongNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found in trash"})
+		case errors.Is(err, repository.ErrDuplicateSong):
+			respondDuplicateSong(c, err)
+		default:
+			respondError(c, err, http.StatusInternalServerError, "Could not restore song")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Song restored successfully"})
+}
+
+// @Summary Permanently delete a song
+// @Description Permanently delete a song that is already in the trash
+// @Tags songs
+// @Param id path int true "Song ID"
+// @Success 204
+// @Failure 400 {object} gin.H{"error": "Invalid song ID"}
+// @Failure 404 {object} gin.H{"error": "Song not found in trash"}
+// @Failure 500 {object} gin.H{"error": "Could not purge song"}
+// @Router /songs/trash/{id} [delete]
+func (h *Handler) PurgeSong(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid song ID"})
+		return
+	}
+
+	if err := h.SongService.PurgeSong(c.Request.Context(), id); err != nil {
+		log.Printf("Error purging song with ID %d: %v", id, err)
+		if errors.Is(err, repository.ErrSongNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Song not found in trash"})
+			return
+		}
+		respondError(c, err, http.StatusInternalServerError, "Could not purge song")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
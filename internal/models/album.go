@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// AlbumType tells full-length albums from EPs, singles and compilations.
+type AlbumType string
+
+const (
+	AlbumLP          AlbumType = "lp"
+	AlbumEP          AlbumType = "ep"
+	AlbumSingle      AlbumType = "single"
+	AlbumCompilation AlbumType = "compilation"
+)
+
+type Album struct {
+	ID          int        `json:"id"`
+	GroupID     int        `json:"-"`
+	Group       string     `json:"group"`
+	Title       string     `json:"title"`
+	ReleaseDate *time.Time `json:"release_date"`
+	Type        AlbumType  `json:"type"`
+	// TrackCount is the number of live songs on the album; songs in the
+	// trash are not counted.
+	TrackCount int `json:"track_count"`
+}
+
+// AlbumTrack is a song at its place on an album.
+type AlbumTrack struct {
+	Disc  int  `json:"disc"`
+	Track int  `json:"track"`
+	Song  Song `json:"song"`
+}
+
+// SongAlbum is an album a song appears on, as listed on the song.
+type SongAlbum struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Type        AlbumType  `json:"type"`
+	ReleaseDate *time.Time `json:"release_date"`
+	Disc        int        `json:"disc"`
+	Track       int        `json:"track"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type Group struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// SongCount is the number of live songs; songs in the trash are not
+	// counted.
+	SongCount int `json:"song_count"`
+	// Aliases are the names of groups merged into this one.
+	Aliases []string `json:"aliases,omitempty"`
+}
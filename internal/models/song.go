@@ -12,4 +12,30 @@ type Song struct {
 	Link        string     `json:"link"`
 	CreatedAt   time.Time  `json:"-"`
 	UpdatedAt   time.Time  `json:"-"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	// Version is bumped on every write and exposed as the song's ETag.
+	Version int `json:"-"`
+	// Albums is only filled when a single song is fetched by ID.
+	Albums []SongAlbum `json:"albums,omitempty"`
+}
+
+type SongSearchResult struct {
+	Song
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type ScoredSong struct {
+	Song
+	Score float64 `json:"score"`
+}
+
+// SongRevision is a snapshot of a song taken after a write, together with
+// the fields that write changed.
+type SongRevision struct {
+	SongID        int       `json:"song_id"`
+	Revision      int       `json:"revision"`
+	Song          Song      `json:"song"`
+	ChangedFields []string  `json:"changed_fields"`
+	CreatedAt     time.Time `json:"created_at"`
 }
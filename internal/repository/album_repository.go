@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"song-library/internal/models"
+	"strings"
+)
+
+// albumColumns is the select list read by albumFields. Callers select from
+// albums as a joined with groups as g.
+const albumColumns = `a.id, a.group_id, g.name, a.title, a.release_date, a.type,
+        (SELECT COUNT(*) FROM album_tracks t JOIN songs s ON s.id = t.song_id WHERE t.album_id = a.id AND s.deleted_at IS NULL)`
+
+func albumFields(album *models.Album) []any {
+	return []any{&album.ID, &album.GroupID, &album.Group, &album.Title, &album.ReleaseDate, &album.Type, &album.TrackCount}
+}
+
+func albumFilterQuery(filter AlbumFilter) *queryBuilder {
+	var b queryBuilder
+	if filter.Title != "" {
+		b.where("a.title ILIKE " + b.arg("%"+filter.Title+"%"))
+	}
+	if filter.Group != "" {
+		b.where("g.name ILIKE " + b.arg("%"+filter.Group+"%"))
+	}
+	return &b
+}
+
+// GetAlbums lists the albums matching filter, ordered by title.
+func (r *SongRepository) GetAlbums(ctx context.Context, filter AlbumFilter, page, limit int) ([]models.Album, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_albums")
+	defer cancel()
+
+	if page < 1 || limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+
+	b := albumFilterQuery(filter)
+	query := `
+        SELECT ` + albumColumns + `
+        FROM albums a
+        JOIN groups g ON a.group_id = g.id
+        ` + b.whereClause() + `
+        ORDER BY a.title, a.id
+        LIMIT ` + b.arg(limit) + ` OFFSET ` + b.arg((page-1)*limit)
+	rows, err := r.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		var album models.Album
+		if err := rows.Scan(albumFields(&album)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		albums = append(albums, album)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return albums, nil
+}
+
+func (r *SongRepository) CountAlbums(ctx context.Context, filter AlbumFilter) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_albums")
+	defer cancel()
+
+	b := albumFilterQuery(filter)
+	query := `SELECT COUNT(*) FROM albums a JOIN groups g ON a.group_id = g.id ` + b.whereClause()
+	var total int
+	if err := r.DB.QueryRowContext(ctx, query, b.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("error counting albums: %w", withContextError(ctx, err))
+	}
+	return total, nil
+}
+
+func (r *SongRepository) GetAlbumByID(ctx context.Context, id int) (*models.Album, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_album")
+	defer cancel()
+
+	return getAlbumByID(ctx, r.DB, id)
+}
+
+func getAlbumByID(ctx context.Context, q dbtx, id int) (*models.Album, error) {
+	query := `
+        SELECT ` + albumColumns + `
+        FROM albums a
+        JOIN groups g ON a.group_id = g.id
+        WHERE a.id = $1
+    `
+	var album models.Album
+	if err := q.QueryRowContext(ctx, query, id).Scan(albumFields(&album)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, fmt.Errorf("error fetching album: %w", withContextError(ctx, err))
+	}
+	return &album, nil
+}
+
+// GetAlbumTracks lists the live songs of an album in disc and track order.
+func (r *SongRepository) GetAlbumTracks(ctx context.Context, id int) ([]models.AlbumTrack, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_album")
+	defer cancel()
+
+	if _, err := getAlbumByID(ctx, r.DB, id); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT t.disc, t.track, ` + songColumns + `
+        FROM album_tracks t
+        JOIN songs s ON s.id = t.song_id
+        JOIN groups g ON s.group_id = g.id
+        WHERE t.album_id = $1 AND s.deleted_at IS NULL
+        ORDER BY t.disc, t.track
+    `
+	rows, err := r.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var tracks []models.AlbumTrack
+	for rows.Next() {
+		var track models.AlbumTrack
+		if err := rows.Scan(append([]any{&track.Disc, &track.Track}, songFields(&track.Song)...)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		tracks = append(tracks, track)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return tracks, nil
+}
+
+// AddAlbum creates an album, and its group when needed, with the given
+// tracks.
+func (r *SongRepository) AddAlbum(ctx context.Context, album models.Album, tracks []TrackPosition) (*models.Album, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "add_album")
+	defer cancel()
+
+	var added *models.Album
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		groupID, err := r.getOrCreateGroupID(ctx, tx, album.Group)
+		if err != nil {
+			return fmt.Errorf("failed to get or create group ID: %w", err)
+		}
+
+		var id int
+		query := `INSERT INTO albums (group_id, title, release_date, type) VALUES ($1, $2, $3, $4) RETURNING id`
+		if err := tx.QueryRowContext(ctx, query, groupID, album.Title, album.ReleaseDate, album.Type).Scan(&id); err != nil {
+			return fmt.Errorf("failed to insert album: %w", withContextError(ctx, err))
+		}
+		if len(tracks) > 0 {
+			if err := setAlbumTracks(ctx, tx, id, tracks); err != nil {
+				return err
+			}
+		}
+		added, err = getAlbumByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully added album %q by group %q", album.Title, album.Group)
+	return added, nil
+}
+
+// UpdateAlbum writes only the fields set in changes. A new title, type or
+// release date changes what the album's songs list, so their versions are
+// bumped along with those of songs added to or dropped from the track list.
+func (r *SongRepository) UpdateAlbum(ctx context.Context, id int, changes AlbumChanges) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "update_album")
+	defer cancel()
+
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		var b queryBuilder
+		var sets []string
+		if changes.Group != nil {
+			groupID, err := r.getOrCreateGroupID(ctx, tx, *changes.Group)
+			if err != nil {
+				return fmt.Errorf("failed to get or create group ID: %w", err)
+			}
+			sets = append(sets, "group_id = "+b.arg(groupID))
+		}
+		if changes.Title != nil {
+			sets = append(sets, "title = "+b.arg(*changes.Title))
+		}
+		if changes.ReleaseDate != nil {
+			if changes.ReleaseDate.IsZero() {
+				sets = append(sets, "release_date = NULL")
+			} else {
+				sets = append(sets, "release_date = "+b.arg(*changes.ReleaseDate))
+			}
+		}
+		if changes.Type != nil {
+			sets = append(sets, "type = "+b.arg(*changes.Type))
+		}
+
+		query := `UPDATE albums SET ` + strings.Join(append(sets, "updated_at = CURRENT_TIMESTAMP"), ", ") + ` WHERE id = ` + b.arg(id)
+		result, err := tx.ExecContext(ctx, query, b.args...)
+		if err != nil {
+			return fmt.Errorf("failed to update album: %w", withContextError(ctx, err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrAlbumNotFound
+		}
+
+		if changes.Tracks != nil {
+			return setAlbumTracks(ctx, tx, id, changes.Tracks)
+		}
+		if changes.Title != nil || changes.ReleaseDate != nil || changes.Type != nil {
+			return touchAlbumSongs(ctx, tx, id, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully updated album with ID %d", id)
+	return nil
+}
+
+// DeleteAlbum deletes an album and its track list; the songs are kept.
+func (r *SongRepository) DeleteAlbum(ctx context.Context, id int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "delete_album")
+	defer cancel()
+
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		if err := touchAlbumSongs(ctx, tx, id, nil); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM albums WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete album: %w", withContextError(ctx, err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		if affected == 0 {
+			return ErrAlbumNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully deleted album with ID %d", id)
+	return nil
+}
+
+// setAlbumTracks replaces the track list of an album. Every song must be
+// live; the songs leaving and joining the album get their versions bumped.
+func setAlbumTracks(ctx context.Context, q dbtx, albumID int, tracks []TrackPosition) error {
+	songIDs := make([]int64, len(tracks))
+	discs := make([]int64, len(tracks))
+	numbers := make([]int64, len(tracks))
+	for i, track := range tracks {
+		songIDs[i], discs[i], numbers[i] = int64(track.SongID), int64(track.Disc), int64(track.Track)
+	}
+
+	query := `
+        SELECT t.id FROM unnest($1::int[]) AS t(id)
+        WHERE NOT EXISTS (SELECT 1 FROM songs s WHERE s.id = t.id AND s.deleted_at IS NULL)
+        LIMIT 1
+    `
+	var missing int
+	err := q.QueryRowContext(ctx, query, pq.Array(songIDs)).Scan(&missing)
+	switch {
+	case err == nil:
+		return fmt.Errorf("%w: song %d", ErrTrackSongNotFound, missing)
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to look up track songs: %w", withContextError(ctx, err))
+	}
+
+	if err := touchAlbumSongs(ctx, q, albumID, songIDs); err != nil {
+		return err
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM album_tracks WHERE album_id = $1`, albumID); err != nil {
+		return fmt.Errorf("failed to clear album tracks: %w", withContextError(ctx, err))
+	}
+	query = `
+        INSERT INTO album_tracks (album_id, song_id, disc, track)
+        SELECT $1, * FROM unnest($2::int[], $3::int[], $4::int[])
+    `
+	if _, err := q.ExecContext(ctx, query, albumID, pq.Array(songIDs), pq.Array(discs), pq.Array(numbers)); err != nil {
+		return fmt.Errorf("failed to insert album tracks: %w", withContextError(ctx, err))
+	}
+	return nil
+}
+
+// touchAlbumSongs bumps the versions of the songs on an album and of the
+// extra songs given, whose album lists are about to change.
+func touchAlbumSongs(ctx context.Context, q dbtx, albumID int, extra []int64) error {
+	query := `
+        UPDATE songs SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id IN (SELECT song_id FROM album_tracks WHERE album_id = $1) OR id = ANY($2)
+    `
+	if _, err := q.ExecContext(ctx, query, albumID, pq.Array(extra)); err != nil {
+		return fmt.Errorf("failed to update songs of album: %w", withContextError(ctx, err))
+	}
+	return nil
+}
+
+// songAlbums lists the albums a song appears on, oldest first.
+func songAlbums(ctx context.Context, q dbtx, songID int) ([]models.SongAlbum, error) {
+	query := `
+        SELECT a.id, a.title, a.type, a.release_date, t.disc, t.track
+        FROM album_tracks t
+        JOIN albums a ON a.id = t.album_id
+        WHERE t.song_id = $1
+        ORDER BY a.release_date NULLS LAST, a.id
+    `
+	rows, err := q.QueryContext(ctx, query, songID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up albums of song: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var albums []models.SongAlbum
+	for rows.Next() {
+		var album models.SongAlbum
+		if err := rows.Scan(&album.ID, &album.Title, &album.Type, &album.ReleaseDate, &album.Disc, &album.Track); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		albums = append(albums, album)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return albums, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"song-library/internal/models"
+	"time"
+)
+
+var (
+	ErrAlbumNotFound     = errors.New("album not found")
+	ErrTrackSongNotFound = errors.New("track song not found")
+)
+
+// AlbumFilter narrows GetAlbums to albums whose title and group name contain
+// the given values; empty values match everything.
+type AlbumFilter struct {
+	Title string
+	Group string
+}
+
+// TrackPosition places a song on an album.
+type TrackPosition struct {
+	SongID int
+	Disc   int
+	Track  int
+}
+
+// AlbumChanges is a partial update of an album. Nil fields are left
+// untouched; a zero ReleaseDate clears the release date. A nil Tracks keeps
+// the track list while a non-nil one, even empty, replaces it.
+type AlbumChanges struct {
+	Group       *string
+	Title       *string
+	ReleaseDate *time.Time
+	Type        *models.AlbumType
+	Tracks      []TrackPosition
+}
+
+func (c AlbumChanges) IsEmpty() bool {
+	return c.Group == nil && c.Title == nil && c.ReleaseDate == nil && c.Type == nil && c.Tracks == nil
+}
+
+// AlbumStore is the persistence contract for albums. Both song stores
+// implement it over the same data, so an album's group is resolved like the
+// group of a song, aliases included, and deleting a group deletes its albums.
+//
+// Track lists only hold live songs: AddAlbum and UpdateAlbum refuse songs
+// that do not exist or are in the trash with ErrTrackSongNotFound, and reads
+// skip tracks whose song has since been moved to the trash. Replacing a track
+// list drops those too.
+//
+// The albums of a song are part of the song returned by GetSongByID, so every
+// album write that changes what a song lists bumps that song's Version.
+type AlbumStore interface {
+	GetAlbums(ctx context.Context, filter AlbumFilter, page, limit int) ([]models.Album, error)
+	CountAlbums(ctx context.Context, filter AlbumFilter) (int, error)
+	GetAlbumByID(ctx context.Context, id int) (*models.Album, error)
+	GetAlbumTracks(ctx context.Context, id int) ([]models.AlbumTrack, error)
+	AddAlbum(ctx context.Context, album models.Album, tracks []TrackPosition) (*models.Album, error)
+	UpdateAlbum(ctx context.Context, id int, changes AlbumChanges) error
+	DeleteAlbum(ctx context.Context, id int) error
+}
+
+var (
+	_ AlbumStore = (*SongRepository)(nil)
+	_ AlbumStore = (*MemorySongRepository)(nil)
+)
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+)
+
+// groupColumns is the select list read by groupFields. Callers join songs as
+// s with LEFT JOIN and GROUP BY g.id.
+const groupColumns = `g.id, g.name, COUNT(s.id) FILTER (WHERE s.deleted_at IS NULL),
+        ARRAY(SELECT ga.alias FROM group_aliases ga WHERE ga.group_id = g.id ORDER BY ga.alias)`
+
+func groupFields(group *models.Group) []any {
+	return []any{&group.ID, &group.Name, &group.SongCount, (*pq.StringArray)(&group.Aliases)}
+}
+
+// isUniqueViolation reports whether err is PostgreSQL's unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// GetGroups lists groups whose name contains name, ordered by name.
+func (r *SongRepository) GetGroups(ctx context.Context, name string, page, limit int) ([]models.Group, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_groups")
+	defer cancel()
+
+	if page < 1 || limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+
+	query := `
+        SELECT ` + groupColumns + `
+        FROM groups g
+        LEFT JOIN songs s ON s.group_id = g.id
+        WHERE g.name ILIKE $1
+        GROUP BY g.id
+        ORDER BY g.name, g.id
+        LIMIT $2 OFFSET $3
+    `
+	rows, err := r.DB.QueryContext(ctx, query, "%"+name+"%", limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(groupFields(&group)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return groups, nil
+}
+
+func (r *SongRepository) CountGroups(ctx context.Context, name string) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_groups")
+	defer cancel()
+
+	var total int
+	query := `SELECT COUNT(*) FROM groups WHERE name ILIKE $1`
+	if err := r.DB.QueryRowContext(ctx, query, "%"+name+"%").Scan(&total); err != nil {
+		return 0, fmt.Errorf("error counting groups: %w", withContextError(ctx, err))
+	}
+	return total, nil
+}
+
+func (r *SongRepository) GetGroupByID(ctx context.Context, id int) (*models.Group, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_group")
+	defer cancel()
+
+	return r.getGroupByID(ctx, r.DB, id)
+}
+
+func (r *SongRepository) getGroupByID(ctx context.Context, q dbtx, id int) (*models.Group, error) {
+	query := `
+        SELECT ` + groupColumns + `
+        FROM groups g
+        LEFT JOIN songs s ON s.group_id = g.id
+        WHERE g.id = $1
+        GROUP BY g.id
+    `
+	var group models.Group
+	if err := q.QueryRowContext(ctx, query, id).Scan(groupFields(&group)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("error fetching group: %w", withContextError(ctx, err))
+	}
+	return &group, nil
+}
+
+// AddGroup creates a group. A name whose key matches another group's name or
+// alias is refused as taken.
+func (r *SongRepository) AddGroup(ctx context.Context, name string) (*models.Group, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "add_group")
+	defer cancel()
+
+	group := models.Group{Name: name}
+	query := `
+        INSERT INTO groups (name, name_key)
+        SELECT $1, $2
+        WHERE NOT EXISTS (SELECT 1 FROM groups WHERE name_key = $2)
+          AND NOT EXISTS (SELECT 1 FROM group_aliases WHERE alias_key = $2 OR alias = $1)
+        ON CONFLICT (name) DO NOTHING
+        RETURNING id
+    `
+	if err := r.DB.QueryRowContext(ctx, query, name, normalize.Key(name)).Scan(&group.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupExists
+		}
+		return nil, fmt.Errorf("failed to insert group: %w", withContextError(ctx, err))
+	}
+
+	log.Printf("Successfully added group %q", name)
+	return &group, nil
+}
+
+// RenameGroup changes a group's name. The name is part of every song of the
+// group, so their versions are bumped and a revision is recorded for each.
+// Renaming a group to one of its own aliases drops the alias, while a name
+// whose key belongs to another group or its aliases is refused as taken.
+func (r *SongRepository) RenameGroup(ctx context.Context, id int, name string) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "update_group")
+	defer cancel()
+
+	key := normalize.Key(name)
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		var taken bool
+		query := `
+            SELECT EXISTS (SELECT 1 FROM groups WHERE (name_key = $2 OR name = $3) AND id <> $1)
+                OR EXISTS (SELECT 1 FROM group_aliases WHERE (alias_key = $2 OR alias = $3) AND group_id <> $1)
+        `
+		if err := tx.QueryRowContext(ctx, query, id, key, name).Scan(&taken); err != nil {
+			return fmt.Errorf("failed to look up group name: %w", withContextError(ctx, err))
+		}
+		if taken {
+			return ErrGroupExists
+		}
+
+		query = `DELETE FROM group_aliases WHERE group_id = $1 AND (alias_key = $2 OR alias = $3)`
+		if _, err := tx.ExecContext(ctx, query, id, key, name); err != nil {
+			return fmt.Errorf("failed to delete group alias: %w", withContextError(ctx, err))
+		}
+
+		query = `UPDATE groups SET name = $2, name_key = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND name <> $2`
+		result, err := tx.ExecContext(ctx, query, id, name, key)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrGroupExists
+			}
+			return fmt.Errorf("failed to rename group: %w", withContextError(ctx, err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		if affected == 0 {
+			// Either the group does not exist or it already has this name.
+			_, err := r.getGroupByID(ctx, tx, id)
+			return err
+		}
+
+		_, err = moveGroupSongs(ctx, tx, []int{id}, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully renamed group with ID %d to %q", id, name)
+	return nil
+}
+
+// DeleteGroup deletes a group and, through ON DELETE CASCADE, all of its songs
+// and albums along with their revisions and tracks. It returns the number of
+// songs deleted.
+func (r *SongRepository) DeleteGroup(ctx context.Context, id int, force bool) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "delete_group")
+	defer cancel()
+
+	var songs int
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		query := `SELECT COUNT(s.id) FROM groups g LEFT JOIN songs s ON s.group_id = g.id WHERE g.id = $1 GROUP BY g.id`
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&songs); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrGroupNotFound
+			}
+			return fmt.Errorf("failed to count songs of group: %w", withContextError(ctx, err))
+		}
+		if songs > 0 && !force {
+			return fmt.Errorf("%w: %d songs, including any in the trash", ErrGroupHasSongs, songs)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete group: %w", withContextError(ctx, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Successfully deleted group with ID %d and %d songs", id, songs)
+	return songs, nil
+}
+
+// MergeGroups moves every song and album of the source groups into the
+// target group, keeps the source names and their aliases as aliases of the
+// target and deletes the source groups. It returns the number of songs
+// moved. Groups whose live songs share a title are not merged, as that would
+// leave the target with duplicates.
+func (r *SongRepository) MergeGroups(ctx context.Context, targetID int, sourceIDs []int) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "update_group")
+	defer cancel()
+
+	var moved int
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		names, err := groupNames(ctx, tx, sourceIDs)
+		if err != nil {
+			return err
+		}
+		if len(names) != len(sourceIDs) {
+			return ErrGroupNotFound
+		}
+		if _, err := r.getGroupByID(ctx, tx, targetID); err != nil {
+			return err
+		}
+
+		var shared int
+		query := `
+            SELECT COUNT(*) FROM (
+                SELECT 1 FROM songs
+                WHERE group_id = ANY($1) AND deleted_at IS NULL
+                GROUP BY COALESCE(title_key, song)
+                HAVING COUNT(*) > 1
+            ) d
+        `
+		if err := tx.QueryRowContext(ctx, query, pq.Array(append([]int{targetID}, sourceIDs...))).Scan(&shared); err != nil {
+			return fmt.Errorf("failed to look up duplicate songs: %w", withContextError(ctx, err))
+		}
+		if shared > 0 {
+			return fmt.Errorf("%w: the groups share %d song titles; delete the extra songs before merging", ErrDuplicateSong, shared)
+		}
+
+		query = `UPDATE group_aliases SET group_id = $1 WHERE group_id = ANY($2)`
+		if _, err := tx.ExecContext(ctx, query, targetID, pq.Array(sourceIDs)); err != nil {
+			return fmt.Errorf("failed to move group aliases: %w", withContextError(ctx, err))
+		}
+		keys := make([]string, len(names))
+		for i, name := range names {
+			keys[i] = normalize.Key(name)
+		}
+		query = `INSERT INTO group_aliases (group_id, alias, alias_key) SELECT $1, * FROM unnest($2::text[], $3::text[])`
+		if _, err := tx.ExecContext(ctx, query, targetID, pq.Array(names), pq.Array(keys)); err != nil {
+			return fmt.Errorf("failed to record group aliases: %w", withContextError(ctx, err))
+		}
+
+		if moved, err = moveGroupSongs(ctx, tx, sourceIDs, targetID); err != nil {
+			return err
+		}
+		query = `UPDATE albums SET group_id = $1, updated_at = CURRENT_TIMESTAMP WHERE group_id = ANY($2)`
+		if _, err := tx.ExecContext(ctx, query, targetID, pq.Array(sourceIDs)); err != nil {
+			return fmt.Errorf("failed to move albums: %w", withContextError(ctx, err))
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM groups WHERE id = ANY($1)`, pq.Array(sourceIDs)); err != nil {
+			return fmt.Errorf("failed to delete merged groups: %w", withContextError(ctx, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Successfully merged groups %v into group %d, moving %d songs", sourceIDs, targetID, moved)
+	return moved, nil
+}
+
+// groupNames returns the names of the groups with the given IDs, skipping IDs
+// that do not exist.
+func groupNames(ctx context.Context, q dbtx, ids []int) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name FROM groups WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up groups: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return names, nil
+}
+
+// moveGroupSongs files the songs of the from groups under group to, bumps
+// their versions and records the new group name as a revision of each. It is
+// also used after a rename, with from holding just the renamed group.
+func moveGroupSongs(ctx context.Context, q dbtx, from []int, to int) (int, error) {
+	query := `
+        UPDATE songs SET group_id = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE group_id = ANY($1)
+        RETURNING id
+    `
+	rows, err := q.QueryContext(ctx, query, pq.Array(from), to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update songs of group: %w", withContextError(ctx, err))
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query = `
+        INSERT INTO song_revisions (song_id, revision, group_name, song, release_date, lyrics, link, changed_fields)
+        SELECT s.id, COALESCE(MAX(sr.revision), 0) + 1, g.name, s.song, s.release_date, s.lyrics, s.link, ARRAY['group']
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        LEFT JOIN song_revisions sr ON sr.song_id = s.id
+        WHERE s.id = ANY($1)
+        GROUP BY s.id, g.name
+    `
+	if _, err := q.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to insert revisions: %w", withContextError(ctx, err))
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"song-library/internal/models"
+)
+
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrGroupExists   = errors.New("group already exists")
+	ErrGroupHasSongs = errors.New("group still has songs")
+)
+
+// GroupStore is the persistence contract for groups. Both song stores
+// implement it over the same data, so a group's songs are the songs whose
+// Group it is.
+//
+// Deleting a group also deletes its songs, including those in the trash, so
+// DeleteGroup refuses with ErrGroupHasSongs unless force is set.
+//
+// MergeGroups keeps the names of merged groups as aliases. Writes that name a
+// song's group by an alias file the song under the group the alias points
+// to, and the SongFilter group condition matches aliases as well as names.
+type GroupStore interface {
+	GetGroups(ctx context.Context, name string, page, limit int) ([]models.Group, error)
+	CountGroups(ctx context.Context, name string) (int, error)
+	GetGroupByID(ctx context.Context, id int) (*models.Group, error)
+	AddGroup(ctx context.Context, name string) (*models.Group, error)
+	RenameGroup(ctx context.Context, id int, name string) error
+	DeleteGroup(ctx context.Context, id int, force bool) (int, error)
+	MergeGroups(ctx context.Context, targetID int, sourceIDs []int) (int, error)
+}
+
+var (
+	_ GroupStore = (*SongRepository)(nil)
+	_ GroupStore = (*MemorySongRepository)(nil)
+)
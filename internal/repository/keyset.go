@@ -0,0 +1,40 @@
+package repository
+
+import "song-library/internal/models"
+
+// Keyset positions a page relative to a row's sort values (see
+// SongSort.Values). A forward keyset returns rows ordered after the position,
+// a backward keyset those ordered before it. Inclusive also returns the row
+// at the position itself. The zero Keyset starts at the beginning.
+type Keyset struct {
+	Values    []string
+	Backward  bool
+	Inclusive bool
+}
+
+func (k Keyset) isStart() bool {
+	return len(k.Values) == 0
+}
+
+// KeysetPage holds one page in sort order. HasMore reports whether further
+// rows exist beyond the page in the direction of travel.
+type KeysetPage struct {
+	Songs   []models.Song
+	HasMore bool
+}
+
+// newKeysetPage trims the extra look-ahead row fetched to detect HasMore and
+// restores sort order for backward pages.
+func newKeysetPage(songs []models.Song, keyset Keyset, limit int) *KeysetPage {
+	page := &KeysetPage{HasMore: len(songs) > limit}
+	if page.HasMore {
+		songs = songs[:limit]
+	}
+	if keyset.Backward {
+		for i, j := 0, len(songs)-1; i < j; i, j = i+1, j-1 {
+			songs[i], songs[j] = songs[j], songs[i]
+		}
+	}
+	page.Songs = songs
+	return page
+}
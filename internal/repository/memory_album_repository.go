@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"song-library/internal/models"
+	"sort"
+	"time"
+)
+
+func (r *MemorySongRepository) GetAlbums(ctx context.Context, filter AlbumFilter, page, limit int) ([]models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if page < 1 || limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	albums := r.matchingAlbums(filter)
+	offset := (page - 1) * limit
+	if offset >= len(albums) {
+		return nil, nil
+	}
+	return albums[offset:min(offset+limit, len(albums))], nil
+}
+
+func (r *MemorySongRepository) CountAlbums(ctx context.Context, filter AlbumFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.matchingAlbums(filter)), nil
+}
+
+func (r *MemorySongRepository) GetAlbumByID(ctx context.Context, id int) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	album, ok := r.albums[id]
+	if !ok {
+		return nil, ErrAlbumNotFound
+	}
+	album = r.albumView(album)
+	return &album, nil
+}
+
+func (r *MemorySongRepository) GetAlbumTracks(ctx context.Context, id int) ([]models.AlbumTrack, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.albums[id]; !ok {
+		return nil, ErrAlbumNotFound
+	}
+	var tracks []models.AlbumTrack
+	for _, position := range r.albumTracks[id] {
+		if song, ok := r.songs[position.SongID]; ok && song.DeletedAt == nil {
+			tracks = append(tracks, models.AlbumTrack{Disc: position.Disc, Track: position.Track, Song: song})
+		}
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].Disc != tracks[j].Disc {
+			return tracks[i].Disc < tracks[j].Disc
+		}
+		return tracks[i].Track < tracks[j].Track
+	})
+	return tracks, nil
+}
+
+func (r *MemorySongRepository) AddAlbum(ctx context.Context, album models.Album, tracks []TrackPosition) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkTrackSongs(tracks); err != nil {
+		return nil, err
+	}
+	r.nextAlbumID++
+	album.ID = r.nextAlbumID
+	album.GroupID = r.getOrCreateGroupID(r.canonicalGroup(album.Group))
+	album.ReleaseDate = truncateToDate(album.ReleaseDate)
+	r.albums[album.ID] = album
+	if len(tracks) > 0 {
+		r.setAlbumTracks(album.ID, tracks)
+	}
+	album = r.albumView(album)
+	return &album, nil
+}
+
+func (r *MemorySongRepository) UpdateAlbum(ctx context.Context, id int, changes AlbumChanges) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	album, ok := r.albums[id]
+	if !ok {
+		return ErrAlbumNotFound
+	}
+	if changes.Tracks != nil {
+		if err := r.checkTrackSongs(changes.Tracks); err != nil {
+			return err
+		}
+	}
+
+	if changes.Group != nil {
+		album.GroupID = r.getOrCreateGroupID(r.canonicalGroup(*changes.Group))
+	}
+	if changes.Title != nil {
+		album.Title = *changes.Title
+	}
+	if changes.ReleaseDate != nil {
+		album.ReleaseDate = nil
+		if !changes.ReleaseDate.IsZero() {
+			album.ReleaseDate = truncateToDate(changes.ReleaseDate)
+		}
+	}
+	if changes.Type != nil {
+		album.Type = *changes.Type
+	}
+	r.albums[id] = album
+
+	if changes.Tracks != nil {
+		r.setAlbumTracks(id, changes.Tracks)
+	} else if changes.Title != nil || changes.ReleaseDate != nil || changes.Type != nil {
+		r.touchAlbumSongs(id, nil)
+	}
+	return nil
+}
+
+func (r *MemorySongRepository) DeleteAlbum(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.albums[id]; !ok {
+		return ErrAlbumNotFound
+	}
+	r.touchAlbumSongs(id, nil)
+	delete(r.albums, id)
+	delete(r.albumTracks, id)
+	return nil
+}
+
+// checkTrackSongs mirrors the SQL store: every track must be a live song. It
+// must be called with the lock held.
+func (r *MemorySongRepository) checkTrackSongs(tracks []TrackPosition) error {
+	for _, track := range tracks {
+		if song, ok := r.songs[track.SongID]; !ok || song.DeletedAt != nil {
+			return fmt.Errorf("%w: song %d", ErrTrackSongNotFound, track.SongID)
+		}
+	}
+	return nil
+}
+
+// setAlbumTracks must be called with the write lock held.
+func (r *MemorySongRepository) setAlbumTracks(albumID int, tracks []TrackPosition) {
+	songIDs := make([]int, len(tracks))
+	for i, track := range tracks {
+		songIDs[i] = track.SongID
+	}
+	r.touchAlbumSongs(albumID, songIDs)
+	r.albumTracks[albumID] = append([]TrackPosition(nil), tracks...)
+}
+
+// touchAlbumSongs bumps the versions of the songs on an album and of the
+// extra songs given. It must be called with the write lock held.
+func (r *MemorySongRepository) touchAlbumSongs(albumID int, extra []int) {
+	touched := make(map[int]bool)
+	for _, track := range r.albumTracks[albumID] {
+		touched[track.SongID] = true
+	}
+	for _, id := range extra {
+		touched[id] = true
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for id := range touched {
+		if song, ok := r.songs[id]; ok {
+			song.Version++
+			song.UpdatedAt = now
+			r.songs[id] = song
+		}
+	}
+}
+
+// dropSongTracks removes a song from every track list, like the cascade of
+// the SQL store. It must be called with the write lock held.
+func (r *MemorySongRepository) dropSongTracks(songID int) {
+	for albumID, tracks := range r.albumTracks {
+		kept := tracks[:0]
+		for _, track := range tracks {
+			if track.SongID != songID {
+				kept = append(kept, track)
+			}
+		}
+		r.albumTracks[albumID] = kept
+	}
+}
+
+// songAlbums lists the albums a song appears on, ordered like the SQL store.
+// It must be called with the lock held.
+func (r *MemorySongRepository) songAlbums(songID int) []models.SongAlbum {
+	var albums []models.SongAlbum
+	for albumID, tracks := range r.albumTracks {
+		for _, track := range tracks {
+			if track.SongID != songID {
+				continue
+			}
+			album := r.albums[albumID]
+			albums = append(albums, models.SongAlbum{
+				ID:          album.ID,
+				Title:       album.Title,
+				Type:        album.Type,
+				ReleaseDate: album.ReleaseDate,
+				Disc:        track.Disc,
+				Track:       track.Track,
+			})
+		}
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		a, b := albums[i].ReleaseDate, albums[j].ReleaseDate
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case (a == nil) != (b == nil):
+			return b == nil
+		}
+		return albums[i].ID < albums[j].ID
+	})
+	return albums
+}
+
+// albumView fills in the group name and track count of a stored album. It
+// must be called with the lock held.
+func (r *MemorySongRepository) albumView(album models.Album) models.Album {
+	album.Group, _ = r.groupName(album.GroupID)
+	album.TrackCount = 0
+	for _, track := range r.albumTracks[album.ID] {
+		if song, ok := r.songs[track.SongID]; ok && song.DeletedAt == nil {
+			album.TrackCount++
+		}
+	}
+	return album
+}
+
+// matchingAlbums returns the albums matching filter, ordered like
+// SongRepository.GetAlbums. It must be called with the lock held.
+func (r *MemorySongRepository) matchingAlbums(filter AlbumFilter) []models.Album {
+	var albums []models.Album
+	for _, album := range r.albums {
+		album = r.albumView(album)
+		if ilike(album.Title, "%"+filter.Title+"%") && ilike(album.Group, "%"+filter.Group+"%") {
+			albums = append(albums, album)
+		}
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		if albums[i].Title != albums[j].Title {
+			return albums[i].Title < albums[j].Title
+		}
+		return albums[i].ID < albums[j].ID
+	})
+	return albums
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+	"sort"
+	"time"
+)
+
+func (r *MemorySongRepository) GetGroups(ctx context.Context, name string, page, limit int) ([]models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if page < 1 || limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := r.matchingGroups(name)
+	offset := (page - 1) * limit
+	if offset >= len(groups) {
+		return nil, nil
+	}
+	return groups[offset:min(offset+limit, len(groups))], nil
+}
+
+func (r *MemorySongRepository) CountGroups(ctx context.Context, name string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.matchingGroups(name)), nil
+}
+
+func (r *MemorySongRepository) GetGroupByID(ctx context.Context, id int) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.groupName(id)
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+	return &models.Group{ID: id, Name: name, SongCount: r.countGroupSongs(id, false), Aliases: r.groupAliases(id)}, nil
+}
+
+func (r *MemorySongRepository) AddGroup(ctx context.Context, name string) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groupByKey(normalize.Key(name), 0); ok {
+		return nil, ErrGroupExists
+	}
+	if _, ok := r.aliases[name]; ok {
+		return nil, ErrGroupExists
+	}
+	return &models.Group{ID: r.getOrCreateGroupID(name), Name: name}, nil
+}
+
+func (r *MemorySongRepository) RenameGroup(ctx context.Context, id int, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.groupName(id)
+	if !ok {
+		return ErrGroupNotFound
+	}
+	if current == name {
+		return nil
+	}
+	key := normalize.Key(name)
+	if _, taken := r.groupByKey(key, id); taken {
+		return ErrGroupExists
+	}
+	if aliasOf, ok := r.aliases[name]; ok && aliasOf != id {
+		return ErrGroupExists
+	}
+	for alias, aliasOf := range r.aliases {
+		if aliasOf == id && (alias == name || normalize.Key(alias) == key) {
+			delete(r.aliases, alias)
+		}
+	}
+	delete(r.groups, current)
+	r.groups[name] = id
+	r.moveGroupSongs(id, id, name)
+	return nil
+}
+
+func (r *MemorySongRepository) DeleteGroup(ctx context.Context, id int, force bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name, ok := r.groupName(id)
+	if !ok {
+		return 0, ErrGroupNotFound
+	}
+	songs := r.countGroupSongs(id, true)
+	if songs > 0 && !force {
+		return 0, fmt.Errorf("%w: %d songs, including any in the trash", ErrGroupHasSongs, songs)
+	}
+
+	for songID, song := range r.songs {
+		if song.GroupID == id {
+			delete(r.songs, songID)
+			delete(r.revisions, songID)
+			r.dropSongTracks(songID)
+		}
+	}
+	for albumID, album := range r.albums {
+		if album.GroupID == id {
+			delete(r.albums, albumID)
+			delete(r.albumTracks, albumID)
+		}
+	}
+	for alias, groupID := range r.aliases {
+		if groupID == id {
+			delete(r.aliases, alias)
+		}
+	}
+	delete(r.groups, name)
+	return songs, nil
+}
+
+func (r *MemorySongRepository) MergeGroups(ctx context.Context, targetID int, sourceIDs []int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target, ok := r.groupName(targetID)
+	if !ok {
+		return 0, ErrGroupNotFound
+	}
+	sources := make([]string, len(sourceIDs))
+	for i, id := range sourceIDs {
+		if sources[i], ok = r.groupName(id); !ok {
+			return 0, ErrGroupNotFound
+		}
+	}
+
+	merged := map[int]bool{targetID: true}
+	for _, id := range sourceIDs {
+		merged[id] = true
+	}
+	titles := make(map[string]int)
+	for _, song := range r.songs {
+		if merged[song.GroupID] && song.DeletedAt == nil {
+			titles[normalize.Key(song.Song)]++
+		}
+	}
+	shared := 0
+	for _, count := range titles {
+		if count > 1 {
+			shared++
+		}
+	}
+	if shared > 0 {
+		return 0, fmt.Errorf("%w: the groups share %d song titles; delete the extra songs before merging", ErrDuplicateSong, shared)
+	}
+
+	moved := 0
+	for i, id := range sourceIDs {
+		for alias, groupID := range r.aliases {
+			if groupID == id {
+				r.aliases[alias] = targetID
+			}
+		}
+		r.aliases[sources[i]] = targetID
+		delete(r.groups, sources[i])
+		moved += r.moveGroupSongs(id, targetID, target)
+		for albumID, album := range r.albums {
+			if album.GroupID == id {
+				album.GroupID = targetID
+				r.albums[albumID] = album
+			}
+		}
+	}
+	return moved, nil
+}
+
+// moveGroupSongs files the songs of group from under group to, named name,
+// bumping their versions and recording a revision of each. It must be called
+// with the write lock held.
+func (r *MemorySongRepository) moveGroupSongs(from, to int, name string) int {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	moved := 0
+	for songID, song := range r.songs {
+		if song.GroupID != from {
+			continue
+		}
+		song.GroupID = to
+		song.Group = name
+		song.UpdatedAt = now
+		song.Version++
+		r.songs[songID] = song
+		r.recordRevision(song)
+		moved++
+	}
+	return moved
+}
+
+// groupAliases must be called with the lock held.
+func (r *MemorySongRepository) groupAliases(id int) []string {
+	var aliases []string
+	for alias, groupID := range r.aliases {
+		if groupID == id {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// groupName must be called with the lock held.
+func (r *MemorySongRepository) groupName(id int) (string, bool) {
+	for name, groupID := range r.groups {
+		if groupID == id {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// countGroupSongs counts the live songs of a group, and the trashed ones too
+// when withTrash is set. It must be called with the lock held.
+func (r *MemorySongRepository) countGroupSongs(id int, withTrash bool) int {
+	count := 0
+	for _, song := range r.songs {
+		if song.GroupID == id && (withTrash || song.DeletedAt == nil) {
+			count++
+		}
+	}
+	return count
+}
+
+// matchingGroups returns the groups whose name contains name, ordered like
+// SongRepository.GetGroups. It must be called with the lock held.
+func (r *MemorySongRepository) matchingGroups(name string) []models.Group {
+	counts := make(map[int]int)
+	for _, song := range r.songs {
+		if song.DeletedAt == nil {
+			counts[song.GroupID]++
+		}
+	}
+
+	var groups []models.Group
+	for groupName, id := range r.groups {
+		if ilike(groupName, "%"+name+"%") {
+			groups = append(groups, models.Group{ID: id, Name: groupName, SongCount: counts[id], Aliases: r.groupAliases(id)})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
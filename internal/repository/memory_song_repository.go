@@ -0,0 +1,803 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySongRepository is a thread-safe in-memory SongStore with the same
+// filtering, pagination and group handling as SongRepository.
+type MemorySongRepository struct {
+	mu          sync.RWMutex
+	groups      map[string]int
+	aliases     map[string]int
+	songs       map[int]models.Song
+	revisions   map[int][]models.SongRevision
+	albums      map[int]models.Album
+	albumTracks map[int][]TrackPosition
+	nextGroupID int
+	nextSongID  int
+	nextAlbumID int
+}
+
+func NewMemorySongRepository() *MemorySongRepository {
+	return &MemorySongRepository{
+		groups:      make(map[string]int),
+		aliases:     make(map[string]int),
+		songs:       make(map[int]models.Song),
+		revisions:   make(map[int][]models.SongRevision),
+		albums:      make(map[int]models.Album),
+		albumTracks: make(map[int][]TrackPosition),
+	}
+}
+
+func (r *MemorySongRepository) GetSongs(ctx context.Context, filter SongFilter, sort SongSort, page, limit int) ([]models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if page < 1 || limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return paginate(r.matchingSongs(filter, sort, false), page, limit), nil
+}
+
+func (r *MemorySongRepository) CountSongs(ctx context.Context, filter SongFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	total := 0
+	matches := r.songMatcher(filter)
+	for _, s := range r.songs {
+		if matches(s) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (r *MemorySongRepository) GetSongsByKeyset(ctx context.Context, filter SongFilter, sort SongSort, keyset Keyset, limit int) (*KeysetPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be greater than 0")
+	}
+	if !keyset.isStart() && len(keyset.Values) != len(sort.normalize()) {
+		return nil, fmt.Errorf("keyset has %d values, sort has %d keys", len(keyset.Values), len(sort.normalize()))
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []models.Song
+	for _, s := range r.matchingSongs(filter, sort, keyset.Backward) {
+		if len(matched) > limit {
+			break
+		}
+		if !keyset.isStart() {
+			c := sort.compareValues(sort.Values(s), keyset.Values)
+			if keyset.Backward {
+				c = -c
+			}
+			if c < 0 || c == 0 && !keyset.Inclusive {
+				continue
+			}
+		}
+		matched = append(matched, s)
+	}
+	return newKeysetPage(matched, keyset, limit), nil
+}
+
+// StreamSongs snapshots the matching songs and then calls fn without holding
+// the lock, so a slow consumer does not block writers.
+func (r *MemorySongRepository) StreamSongs(ctx context.Context, filter SongFilter, sort SongSort, omitLyrics bool, fn func(song models.Song) error) error {
+	r.mu.RLock()
+	songs := r.matchingSongs(filter, sort, false)
+	r.mu.RUnlock()
+
+	for _, song := range songs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if omitLyrics {
+			song.Lyrics = ""
+		}
+		if err := fn(song); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemorySongRepository) GetSongByID(ctx context.Context, songID string) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(songID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid song ID %q: %w", songID, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	song, ok := r.songs[id]
+	if !ok || song.DeletedAt != nil {
+		return nil, ErrSongNotFound
+	}
+	song.Albums = r.songAlbums(id)
+	return &song, nil
+}
+
+func (r *MemorySongRepository) AddSong(ctx context.Context, song models.Song) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolveGroup(&song)
+	if err := r.checkDuplicateSong(song.GroupID, song.Song, 0); err != nil {
+		return nil, err
+	}
+	return r.insertSong(song), nil
+}
+
+func (r *MemorySongRepository) UpsertSong(ctx context.Context, song models.Song) (*models.Song, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolveGroup(&song)
+	var duplicate *DuplicateSongError
+	if err := r.checkDuplicateSong(song.GroupID, song.Song, 0); !errors.As(err, &duplicate) {
+		return r.insertSong(song), true, nil
+	}
+	r.replaceSong(duplicate.ExistingID, song)
+	stored := r.songs[duplicate.ExistingID]
+	stored.Albums = r.songAlbums(stored.ID)
+	return &stored, false, nil
+}
+
+// insertSong stores a new song whose group is resolved. It must be called
+// with the write lock held.
+func (r *MemorySongRepository) insertSong(song models.Song) *models.Song {
+	r.nextSongID++
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	song.ID = r.nextSongID
+	song.ReleaseDate = truncateToDate(song.ReleaseDate)
+	song.CreatedAt = now
+	song.UpdatedAt = now
+	song.Version = 1
+	r.songs[song.ID] = song
+	r.recordRevision(song)
+	return &song
+}
+
+func (r *MemorySongRepository) AddSongs(ctx context.Context, songs []models.Song, opts BulkOptions) ([]BulkOutcome, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := r.liveSongKeys()
+	outcomes := make([]BulkOutcome, len(songs))
+	firstInBatch := make(map[songKey]int)
+	repeats := make(map[int]int)
+	duplicates := 0
+	for i, song := range songs {
+		key := songKey{normalize.Key(r.canonicalGroup(song.Group)), normalize.Key(song.Song)}
+		if id, ok := existing[key]; ok {
+			outcomes[i].DuplicateOf = id
+			duplicates++
+			continue
+		}
+		if first, ok := firstInBatch[key]; ok {
+			repeats[i] = first
+			duplicates++
+			continue
+		}
+		firstInBatch[key] = i
+	}
+	if opts.AllOrNothing && !opts.UpdateDuplicates && duplicates > 0 {
+		return outcomes, nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i, song := range songs {
+		if _, ok := repeats[i]; ok {
+			continue
+		}
+		if outcomes[i].DuplicateOf != 0 {
+			if opts.UpdateDuplicates {
+				r.replaceSong(outcomes[i].DuplicateOf, song)
+				outcomes[i].ID, outcomes[i].Updated = outcomes[i].DuplicateOf, true
+			}
+			continue
+		}
+		r.nextSongID++
+		song.ID = r.nextSongID
+		r.resolveGroup(&song)
+		song.ReleaseDate = truncateToDate(song.ReleaseDate)
+		song.CreatedAt = now
+		song.UpdatedAt = now
+		song.Version = 1
+		r.songs[song.ID] = song
+		r.recordRevision(song)
+		outcomes[i].ID = song.ID
+	}
+	for i, first := range repeats {
+		outcomes[i].DuplicateOf = outcomes[first].ID
+	}
+	return outcomes, nil
+}
+
+func (r *MemorySongRepository) FindDuplicateSongs(ctx context.Context, songs []models.Song) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	existing := r.liveSongKeys()
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		ids[i] = existing[songKey{normalize.Key(r.canonicalGroup(song.Group)), normalize.Key(song.Song)}]
+	}
+	return ids, nil
+}
+
+// liveSongKeys maps the group and title keys of every live song to the
+// oldest song holding them. It must be called with the lock held.
+func (r *MemorySongRepository) liveSongKeys() map[songKey]int {
+	existing := make(map[songKey]int)
+	for _, song := range r.sortedSongs() {
+		key := songKey{normalize.Key(song.Group), normalize.Key(song.Song)}
+		if _, ok := existing[key]; !ok && song.DeletedAt == nil {
+			existing[key] = song.ID
+		}
+	}
+	return existing
+}
+
+func (r *MemorySongRepository) UpdateSong(ctx context.Context, id int, song models.Song, ifVersion int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.songs[id]
+	if err := checkVersion(existing, ok, ifVersion); err != nil {
+		return err
+	}
+	if !ok || existing.DeletedAt != nil {
+		return ErrSongNotFound
+	}
+	r.resolveGroup(&song)
+	if err := r.checkDuplicateSong(song.GroupID, song.Song, id); err != nil {
+		return err
+	}
+	r.replaceSong(id, song)
+	return nil
+}
+
+// replaceSong overwrites the content of song id with song, filed under its
+// group. It must be called with the write lock held.
+func (r *MemorySongRepository) replaceSong(id int, song models.Song) {
+	r.resolveGroup(&song)
+	existing := r.songs[id]
+	existing.GroupID = song.GroupID
+	existing.Group = song.Group
+	existing.Song = song.Song
+	existing.ReleaseDate = truncateToDate(song.ReleaseDate)
+	existing.Lyrics = song.Lyrics
+	existing.Link = song.Link
+	existing.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	existing.Version++
+	r.songs[id] = existing
+	r.recordRevision(existing)
+}
+
+func (r *MemorySongRepository) PatchSong(ctx context.Context, id int, changes SongChanges, ifVersion int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.songs[id]
+	if !ok || existing.DeletedAt != nil {
+		return ErrSongNotFound
+	}
+	if err := checkVersion(existing, ok, ifVersion); err != nil {
+		return err
+	}
+
+	if changes.Group != nil {
+		existing.Group = *changes.Group
+		r.resolveGroup(&existing)
+	}
+	if changes.Song != nil {
+		existing.Song = *changes.Song
+	}
+	if changes.Group != nil || changes.Song != nil {
+		if err := r.checkDuplicateSong(existing.GroupID, existing.Song, id); err != nil {
+			return err
+		}
+	}
+	if changes.ReleaseDate != nil {
+		existing.ReleaseDate = truncateToDate(changes.ReleaseDate)
+	}
+	if changes.Lyrics != nil {
+		existing.Lyrics = *changes.Lyrics
+	}
+	if changes.Link != nil {
+		existing.Link = *changes.Link
+	}
+	existing.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	existing.Version++
+	r.songs[id] = existing
+	r.recordRevision(existing)
+	return nil
+}
+
+func (r *MemorySongRepository) DeleteSong(ctx context.Context, id int, ifVersion int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[id]
+	if err := checkVersion(song, ok, ifVersion); err != nil {
+		return err
+	}
+	if !ok || song.DeletedAt != nil {
+		return ErrSongNotFound
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	song.DeletedAt = &now
+	song.Version++
+	r.songs[id] = song
+	return nil
+}
+
+func (r *MemorySongRepository) RestoreSong(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[id]
+	if !ok || song.DeletedAt == nil {
+		return ErrSongNotFound
+	}
+	if err := r.checkDuplicateSong(song.GroupID, song.Song, id); err != nil {
+		return err
+	}
+	song.DeletedAt = nil
+	song.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	song.Version++
+	r.songs[id] = song
+	return nil
+}
+
+func (r *MemorySongRepository) PurgeSong(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	song, ok := r.songs[id]
+	if !ok || song.DeletedAt == nil {
+		return ErrSongNotFound
+	}
+	delete(r.songs, id)
+	delete(r.revisions, id)
+	r.dropSongTracks(id)
+	return nil
+}
+
+func (r *MemorySongRepository) PurgeDeletedSongs(ctx context.Context, cutoff time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, song := range r.songs {
+		if song.DeletedAt != nil && song.DeletedAt.Before(cutoff) {
+			delete(r.songs, id)
+			delete(r.revisions, id)
+			r.dropSongTracks(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *MemorySongRepository) GetIncompleteSongs(ctx context.Context, afterID, limit int) ([]models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var songs []models.Song
+	for _, s := range r.sortedSongs() {
+		if s.ID > afterID && s.DeletedAt == nil && isIncomplete(s) {
+			songs = append(songs, s)
+		}
+		if len(songs) == limit {
+			break
+		}
+	}
+	return songs, nil
+}
+
+func (r *MemorySongRepository) FillMissingFields(ctx context.Context, id int, song models.Song) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.songs[id]
+	if !ok || existing.DeletedAt != nil {
+		return false, nil
+	}
+
+	song = withoutEdited(song, editedFields(r.revisions[id]))
+	changed := false
+	if existing.Lyrics == "" && song.Lyrics != "" {
+		existing.Lyrics = song.Lyrics
+		changed = true
+	}
+	if existing.Link == "" && song.Link != "" {
+		existing.Link = song.Link
+		changed = true
+	}
+	if isZeroDate(existing.ReleaseDate) && song.ReleaseDate != nil {
+		existing.ReleaseDate = truncateToDate(song.ReleaseDate)
+		changed = true
+	}
+	if changed {
+		existing.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		existing.Version++
+		r.songs[id] = existing
+		r.recordRevision(existing)
+	}
+	return changed, nil
+}
+
+func (r *MemorySongRepository) GetSongRevisions(ctx context.Context, songID int) ([]models.SongRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if song, ok := r.songs[songID]; !ok || song.DeletedAt != nil {
+		return nil, ErrSongNotFound
+	}
+	history := r.revisions[songID]
+	revisions := make([]models.SongRevision, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		revisions = append(revisions, history[i])
+	}
+	return revisions, nil
+}
+
+func (r *MemorySongRepository) GetSongRevision(ctx context.Context, songID, revision int) (*models.SongRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.songRevision(songID, revision)
+}
+
+func (r *MemorySongRepository) RollbackSong(ctx context.Context, songID, revision int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	target, err := r.songRevision(songID, revision)
+	if err != nil {
+		return err
+	}
+
+	existing := r.songs[songID]
+	existing.Group = target.Song.Group
+	r.resolveGroup(&existing)
+	existing.Song = target.Song.Song
+	if err := r.checkDuplicateSong(existing.GroupID, existing.Song, songID); err != nil {
+		return err
+	}
+	existing.ReleaseDate = target.Song.ReleaseDate
+	existing.Lyrics = target.Song.Lyrics
+	existing.Link = target.Song.Link
+	existing.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	existing.Version++
+	r.songs[songID] = existing
+	r.recordRevision(existing)
+	return nil
+}
+
+// checkVersion applies the ifVersion precondition of UpdateSong and
+// DeleteSong to a looked-up song.
+func checkVersion(song models.Song, ok bool, ifVersion int) error {
+	if ifVersion == 0 {
+		return nil
+	}
+	if !ok || song.DeletedAt != nil {
+		return ErrSongNotFound
+	}
+	if song.Version != ifVersion {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// songRevision must be called with the lock held.
+func (r *MemorySongRepository) songRevision(songID, revision int) (*models.SongRevision, error) {
+	if song, ok := r.songs[songID]; !ok || song.DeletedAt != nil {
+		return nil, ErrSongNotFound
+	}
+	history := r.revisions[songID]
+	if revision < 1 || revision > len(history) {
+		return nil, ErrRevisionNotFound
+	}
+	result := history[revision-1]
+	return &result, nil
+}
+
+// recordRevision appends song to its history unless none of the tracked
+// fields changed. It must be called with the write lock held.
+func (r *MemorySongRepository) recordRevision(song models.Song) {
+	history := r.revisions[song.ID]
+	var prev *models.Song
+	if len(history) > 0 {
+		prev = &history[len(history)-1].Song
+	}
+	changed := changedFields(prev, song)
+	if len(changed) == 0 {
+		return
+	}
+
+	snapshot := models.Song{
+		ID:          song.ID,
+		Group:       song.Group,
+		Song:        song.Song,
+		ReleaseDate: song.ReleaseDate,
+		Lyrics:      song.Lyrics,
+		Link:        song.Link,
+	}
+	r.revisions[song.ID] = append(history, models.SongRevision{
+		SongID:        song.ID,
+		Revision:      len(history) + 1,
+		Song:          snapshot,
+		ChangedFields: changed,
+		CreatedAt:     song.UpdatedAt,
+	})
+}
+
+// resolveGroup files song under the group named by song.Group, following
+// aliases and creating the group when needed. It must be called with the
+// write lock held.
+func (r *MemorySongRepository) resolveGroup(song *models.Song) {
+	song.Group = r.canonicalGroup(song.Group)
+	song.GroupID = r.getOrCreateGroupID(song.Group)
+}
+
+// canonicalGroup returns the name of the group whose name or alias has the
+// same name key as name, or name itself when there is none. It must be called
+// with the lock held.
+func (r *MemorySongRepository) canonicalGroup(name string) string {
+	if id, ok := r.groupByKey(normalize.Key(name), 0); ok {
+		canonical, _ := r.groupName(id)
+		return canonical
+	}
+	return name
+}
+
+// groupByKey returns the ID of a group other than except whose name key is
+// key, or failing that of a group with an alias of that key. Like the
+// SQL store it prefers the oldest group. It must be called with the lock
+// held.
+func (r *MemorySongRepository) groupByKey(key string, except int) (int, bool) {
+	found := 0
+	for name, id := range r.groups {
+		if id != except && (found == 0 || id < found) && normalize.Key(name) == key {
+			found = id
+		}
+	}
+	if found != 0 {
+		return found, true
+	}
+	for alias, id := range r.aliases {
+		if id != except && (found == 0 || id < found) && normalize.Key(alias) == key {
+			found = id
+		}
+	}
+	return found, found != 0
+}
+
+// getOrCreateGroupID must be called with the write lock held.
+func (r *MemorySongRepository) getOrCreateGroupID(groupName string) int {
+	if id, ok := r.groups[groupName]; ok {
+		return id
+	}
+	r.nextGroupID++
+	r.groups[groupName] = r.nextGroupID
+	return r.nextGroupID
+}
+
+// checkDuplicateSong mirrors the SQL store's check: it returns a
+// *DuplicateSongError when a live song of group groupID other than exceptID
+// has the title key of title. It must be called with the lock held.
+func (r *MemorySongRepository) checkDuplicateSong(groupID int, title string, exceptID int) error {
+	key := normalize.Key(title)
+	existingID := 0
+	for id, song := range r.songs {
+		if id == exceptID || song.GroupID != groupID || song.DeletedAt != nil {
+			continue
+		}
+		if (existingID == 0 || id < existingID) && normalize.Key(song.Song) == key {
+			existingID = id
+		}
+	}
+	if existingID == 0 {
+		return nil
+	}
+	return &DuplicateSongError{ExistingID: existingID}
+}
+
+// sortedSongs must be called with the lock held.
+func (r *MemorySongRepository) sortedSongs() []models.Song {
+	songs := make([]models.Song, 0, len(r.songs))
+	for _, s := range r.songs {
+		songs = append(songs, s)
+	}
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+	return songs
+}
+
+// songMatcher returns filter's matcher with the group condition extended to
+// aliases. It must be called with the lock held.
+func (r *MemorySongRepository) songMatcher(filter SongFilter) func(song models.Song) bool {
+	if filter.Group != "" {
+		filter.aliasedGroups = make(map[int]bool)
+		for alias, id := range r.aliases {
+			if ilike(alias, "%"+filter.Group+"%") {
+				filter.aliasedGroups[id] = true
+			}
+		}
+	}
+	return filter.matcher()
+}
+
+// matchingSongs returns the songs matching filter ordered by songSort, or in
+// reverse order when backward is set. It must be called with the lock held.
+func (r *MemorySongRepository) matchingSongs(filter SongFilter, songSort SongSort, backward bool) []models.Song {
+	var songs []models.Song
+	matches := r.songMatcher(filter)
+	for _, s := range r.songs {
+		if matches(s) {
+			songs = append(songs, s)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		c := songSort.compare(songs[i], songs[j])
+		if backward {
+			return c > 0
+		}
+		return c < 0
+	})
+	return songs
+}
+
+func paginate(songs []models.Song, page, limit int) []models.Song {
+	offset := (page - 1) * limit
+	if offset >= len(songs) {
+		return nil
+	}
+	end := offset + limit
+	if end > len(songs) {
+		end = len(songs)
+	}
+	return songs[offset:end]
+}
+
+func isIncomplete(song models.Song) bool {
+	return song.Lyrics == "" || song.Link == "" || isZeroDate(song.ReleaseDate)
+}
+
+func isZeroDate(date *time.Time) bool {
+	return date == nil || date.IsZero()
+}
+
+// truncateToDate mirrors the DATE column type, which drops the time of day.
+func truncateToDate(date *time.Time) *time.Time {
+	if date == nil {
+		return nil
+	}
+	truncated := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return &truncated
+}
+
+// ilike reports whether value matches a SQL ILIKE pattern, where % matches any
+// run of characters, _ matches a single character and \ escapes the next one.
+func ilike(value, pattern string) bool {
+	return likeMatch([]rune(strings.ToLower(value)), []rune(strings.ToLower(pattern)))
+}
+
+func likeMatch(value, pattern []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '%':
+			for len(pattern) > 0 && pattern[0] == '%' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(value); i++ {
+				if likeMatch(value[i:], pattern) {
+					return true
+				}
+			}
+			return false
+		case '_':
+			if len(value) == 0 {
+				return false
+			}
+			value, pattern = value[1:], pattern[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(value) == 0 || value[0] != pattern[0] {
+				return false
+			}
+			value, pattern = value[1:], pattern[1:]
+		}
+	}
+	return len(value) == 0
+}
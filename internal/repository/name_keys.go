@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+	"sort"
+)
+
+// backfillBatchSize bounds the number of rows read and updated at once.
+const backfillBatchSize = 500
+
+// NameKeyCollision lists groups that share a name key. Writes resolve the key
+// to the oldest of them, so the others should be merged into it.
+type NameKeyCollision struct {
+	Key    string
+	Groups []models.Group
+}
+
+// TitleKeyCollision is a live song whose title key could not be stored
+// because another live song of its group already has it. One of the two has
+// to be deleted or renamed before the backfill is run again.
+type TitleKeyCollision struct {
+	Song        models.Song
+	DuplicateOf int
+}
+
+// NameKeyBackfill reports what BackfillNameKeys did.
+type NameKeyBackfill struct {
+	Groups          int
+	Aliases         int
+	Songs           int
+	Collisions      []NameKeyCollision
+	TitleCollisions []TitleKeyCollision
+}
+
+// BackfillNameKeys computes the name key of every group and alias and the
+// title key of every song, storing those that are missing or stale, and
+// reports groups whose keys collide and songs that duplicate another. Each
+// batch commits on its own, so the backfill can be rerun after a failure.
+func (r *SongRepository) BackfillNameKeys(ctx context.Context) (*NameKeyBackfill, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "backfill_name_keys")
+	defer cancel()
+
+	var result NameKeyBackfill
+	var err error
+	result.Groups, err = r.backfillKeys(ctx, `groups`, `name`, `name_key`)
+	if err != nil {
+		return nil, err
+	}
+	result.Aliases, err = r.backfillKeys(ctx, `group_aliases`, `alias`, `alias_key`)
+	if err != nil {
+		return nil, err
+	}
+	if result.Collisions, err = r.nameKeyCollisions(ctx); err != nil {
+		return nil, err
+	}
+	result.Songs, result.TitleCollisions, err = r.backfillTitleKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Backfilled %d group, %d alias and %d title keys, found %d group and %d song collisions",
+		result.Groups, result.Aliases, result.Songs, len(result.Collisions), len(result.TitleCollisions))
+	return &result, nil
+}
+
+// backfillKeys walks table by id and sets keyColumn to the name key of
+// nameColumn where it differs. It returns the number of rows updated.
+func (r *SongRepository) backfillKeys(ctx context.Context, table, nameColumn, keyColumn string) (int, error) {
+	selectQuery := fmt.Sprintf(`SELECT id, %s, %s FROM %s WHERE id > $1 ORDER BY id LIMIT $2`, nameColumn, keyColumn, table)
+	updateQuery := fmt.Sprintf(`
+        UPDATE %[1]s t SET %[2]s = k.key
+        FROM unnest($1::int[], $2::text[]) AS k(id, key)
+        WHERE t.id = k.id
+    `, table, keyColumn)
+
+	updated, lastID := 0, 0
+	for {
+		rows, err := r.DB.QueryContext(ctx, selectQuery, lastID, backfillBatchSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", table, withContextError(ctx, err))
+		}
+		var ids []int64
+		var keys []string
+		read := 0
+		for rows.Next() {
+			var name string
+			var key sql.NullString
+			if err := rows.Scan(&lastID, &name, &key); err != nil {
+				rows.Close()
+				return 0, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+			}
+			read++
+			if want := normalize.Key(name); !key.Valid || key.String != want {
+				ids = append(ids, int64(lastID))
+				keys = append(keys, want)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+		}
+
+		if len(ids) > 0 {
+			if _, err := r.DB.ExecContext(ctx, updateQuery, pq.Array(ids), pq.Array(keys)); err != nil {
+				return 0, fmt.Errorf("failed to update %s: %w", table, withContextError(ctx, err))
+			}
+			updated += len(ids)
+		}
+		if read < backfillBatchSize {
+			return updated, nil
+		}
+	}
+}
+
+// backfillTitleKeys walks songs by id and sets their title keys where they
+// differ. A live song whose key another live song of its group already has
+// keeps its old key and is reported instead, oldest songs winning.
+func (r *SongRepository) backfillTitleKeys(ctx context.Context) (int, []TitleKeyCollision, error) {
+	selectQuery := `
+        SELECT s.id, s.group_id, g.name, s.song, s.title_key, s.deleted_at IS NULL
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        WHERE s.id > $1
+        ORDER BY s.id
+        LIMIT $2
+    `
+	updateQuery := `
+        UPDATE songs s SET title_key = k.key
+        FROM unnest($1::int[], $2::text[]) AS k(id, key)
+        WHERE s.id = k.id AND (s.deleted_at IS NOT NULL OR NOT EXISTS (
+            SELECT 1 FROM songs o
+            WHERE o.group_id = s.group_id AND o.title_key = k.key AND o.id <> s.id AND o.deleted_at IS NULL
+        ))
+        RETURNING s.id
+    `
+
+	var collisions []TitleKeyCollision
+	updated, lastID := 0, 0
+	for {
+		rows, err := r.DB.QueryContext(ctx, selectQuery, lastID, backfillBatchSize)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read songs: %w", withContextError(ctx, err))
+		}
+		var ids []int64
+		var keys []string
+		pending := make(map[int]models.Song)
+		claimed := make(map[groupSongKey]int)
+		read := 0
+		for rows.Next() {
+			var song models.Song
+			var key sql.NullString
+			var live bool
+			if err := rows.Scan(&song.ID, &song.GroupID, &song.Group, &song.Song, &key, &live); err != nil {
+				rows.Close()
+				return 0, nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+			}
+			lastID = song.ID
+			read++
+			want := normalize.Key(song.Song)
+			if live {
+				// Two live songs of this batch with the same key would make
+				// the UPDATE itself violate the unique index.
+				claim := groupSongKey{song.GroupID, want}
+				if first, ok := claimed[claim]; ok {
+					collisions = append(collisions, TitleKeyCollision{Song: song, DuplicateOf: first})
+					continue
+				}
+				claimed[claim] = song.ID
+			}
+			if !key.Valid || key.String != want {
+				ids = append(ids, int64(song.ID))
+				keys = append(keys, want)
+				pending[song.ID] = song
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+		}
+
+		if len(ids) > 0 {
+			rows, err := r.DB.QueryContext(ctx, updateQuery, pq.Array(ids), pq.Array(keys))
+			if err != nil {
+				return 0, nil, fmt.Errorf("failed to update songs: %w", withContextError(ctx, err))
+			}
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return 0, nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+				}
+				delete(pending, id)
+				updated++
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return 0, nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+			}
+		}
+
+		// Whatever is still pending lost to a song of an earlier batch.
+		for _, song := range pending {
+			err := checkDuplicateSong(ctx, r.DB, song.GroupID, song.Song, song.ID)
+			var duplicate *DuplicateSongError
+			if !errors.As(err, &duplicate) {
+				if err != nil {
+					return 0, nil, err
+				}
+				continue
+			}
+			collisions = append(collisions, TitleKeyCollision{Song: song, DuplicateOf: duplicate.ExistingID})
+		}
+		if read < backfillBatchSize {
+			sort.Slice(collisions, func(i, j int) bool { return collisions[i].Song.ID < collisions[j].Song.ID })
+			return updated, collisions, nil
+		}
+	}
+}
+
+// nameKeyCollisions returns the groups sharing a name key with another group,
+// oldest first.
+func (r *SongRepository) nameKeyCollisions(ctx context.Context) ([]NameKeyCollision, error) {
+	query := `
+        SELECT g.name_key, g.id, g.name
+        FROM groups g
+        WHERE g.name_key IN (SELECT name_key FROM groups GROUP BY name_key HAVING COUNT(*) > 1)
+        ORDER BY g.name_key, g.id
+    `
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up name key collisions: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var collisions []NameKeyCollision
+	for rows.Next() {
+		var key string
+		var group models.Group
+		if err := rows.Scan(&key, &group.ID, &group.Name); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		if len(collisions) == 0 || collisions[len(collisions)-1].Key != key {
+			collisions = append(collisions, NameKeyCollision{Key: key})
+		}
+		last := &collisions[len(collisions)-1]
+		last.Groups = append(last.Groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return collisions, nil
+}
@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+)
+
+// bulkChunkSize bounds the number of rows sent in a single statement.
+const bulkChunkSize = 1000
+
+// BulkOptions controls how AddSongs treats songs that duplicate a live one.
+// With AllOrNothing set, any such duplicate aborts the batch and nothing is
+// written. With UpdateDuplicates set, a duplicate instead replaces the song
+// it duplicates, like UpdateSong, and never aborts the batch.
+type BulkOptions struct {
+	AllOrNothing     bool
+	UpdateDuplicates bool
+}
+
+// BulkOutcome reports what AddSongs did with one song: ID is set when it was
+// inserted and DuplicateOf when a live song with the same group and title
+// already exists. Updated is set, along with both IDs, when the existing
+// song was replaced.
+type BulkOutcome struct {
+	ID          int
+	DuplicateOf int
+	Updated     bool
+}
+
+// songKey identifies a song by group name key and title key for duplicate
+// checks.
+type songKey struct {
+	group string
+	song  string
+}
+
+// groupSongKey identifies an inserted row by group ID and title.
+type groupSongKey struct {
+	groupID int
+	song    string
+}
+
+// AddSongs inserts songs in a single transaction, resolving their groups in
+// batch and skipping or updating songs that duplicate a live one as opts
+// says. A song repeating an earlier song of the batch is never written and
+// reports that song as DuplicateOf. Outcomes are returned in input order.
+func (r *SongRepository) AddSongs(ctx context.Context, songs []models.Song, opts BulkOptions) ([]BulkOutcome, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "add_songs")
+	defer cancel()
+
+	var outcomes []BulkOutcome
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		outcomes = make([]BulkOutcome, len(songs))
+		duplicates, err := r.findDuplicates(ctx, tx, songs)
+		if err != nil {
+			return err
+		}
+		pending := make([]int, 0, len(songs))
+		firstInBatch := make(map[songKey]int)
+		repeats := make(map[int]int)
+		for i, song := range songs {
+			key := songKey{normalize.Key(song.Group), normalize.Key(song.Song)}
+			if id, ok := duplicates[key]; ok {
+				outcomes[i].DuplicateOf = id
+				continue
+			}
+			if first, ok := firstInBatch[key]; ok {
+				repeats[i] = first
+				continue
+			}
+			firstInBatch[key] = i
+			pending = append(pending, i)
+		}
+		if opts.AllOrNothing && !opts.UpdateDuplicates && len(pending) < len(songs) {
+			return nil
+		}
+		if opts.UpdateDuplicates {
+			for i, song := range songs {
+				if outcomes[i].DuplicateOf == 0 {
+					continue
+				}
+				if err := r.updateSong(ctx, tx, outcomes[i].DuplicateOf, song, 0); err != nil {
+					return err
+				}
+				outcomes[i].ID, outcomes[i].Updated = outcomes[i].DuplicateOf, true
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		groupIDs, err := r.resolveGroups(ctx, tx, songs)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(pending); start += bulkChunkSize {
+			end := min(start+bulkChunkSize, len(pending))
+			if err := r.insertChunk(ctx, tx, songs, pending[start:end], groupIDs, outcomes); err != nil {
+				return err
+			}
+		}
+		for i, first := range repeats {
+			outcomes[i].DuplicateOf = outcomes[first].ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Bulk insert processed %d songs", len(songs))
+	return outcomes, nil
+}
+
+// FindDuplicateSongs returns, for each of songs, the ID of the live song
+// AddSongs would report it as a duplicate of, or 0. Repeats within songs
+// are not reported.
+func (r *SongRepository) FindDuplicateSongs(ctx context.Context, songs []models.Song) ([]int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "find_duplicate_songs")
+	defer cancel()
+
+	duplicates, err := r.findDuplicates(ctx, r.DB, songs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		ids[i] = duplicates[songKey{normalize.Key(song.Group), normalize.Key(song.Song)}]
+	}
+	return ids, nil
+}
+
+// findDuplicates returns the IDs of live songs sharing a group and title
+// with any of songs. Groups and titles are matched by name key, so they may
+// differ in case or spacing, and a group name may be an alias of the group.
+func (r *SongRepository) findDuplicates(ctx context.Context, q dbtx, songs []models.Song) (map[songKey]int, error) {
+	groups := make([]string, len(songs))
+	groupKeys := make([]string, len(songs))
+	titles := make([]string, len(songs))
+	titleKeys := make([]string, len(songs))
+	for i, song := range songs {
+		groups[i], groupKeys[i] = song.Group, normalize.Key(song.Group)
+		titles[i], titleKeys[i] = song.Song, normalize.Key(song.Song)
+	}
+
+	query := `
+        SELECT MIN(s.id), k.group_key, k.title_key
+        FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS k(group_name, group_key, song, title_key)
+        LEFT JOIN group_aliases ga ON ga.alias_key = k.group_key OR ga.alias = k.group_name
+        JOIN groups g ON g.name_key = k.group_key OR g.name = k.group_name OR g.id = ga.group_id
+        JOIN songs s ON s.group_id = g.id AND (s.title_key = k.title_key OR s.song = k.song)
+        WHERE s.deleted_at IS NULL
+        GROUP BY k.group_key, k.title_key
+    `
+	rows, err := q.QueryContext(ctx, query, pq.Array(groups), pq.Array(groupKeys), pq.Array(titles), pq.Array(titleKeys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up duplicates: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	duplicates := make(map[songKey]int)
+	for rows.Next() {
+		var id int
+		var key songKey
+		if err := rows.Scan(&id, &key.group, &key.song); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		duplicates[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return duplicates, nil
+}
+
+// resolveGroups creates any missing groups and maps the name key of every
+// group used by songs to its ID. Keys of aliases map to the group they point
+// to. Of several names sharing a key, the first one names a new group.
+func (r *SongRepository) resolveGroups(ctx context.Context, q dbtx, songs []models.Song) (map[string]int, error) {
+	seen := make(map[string]bool)
+	var names, keys []string
+	for _, song := range songs {
+		key := normalize.Key(song.Group)
+		if !seen[key] {
+			seen[key] = true
+			names = append(names, song.Group)
+			keys = append(keys, key)
+		}
+	}
+
+	groupIDs, err := r.lookUpGroupKeys(ctx, q, names, keys)
+	if err != nil {
+		return nil, err
+	}
+	var missingNames, missingKeys []string
+	for i, key := range keys {
+		if _, ok := groupIDs[key]; !ok {
+			missingNames = append(missingNames, names[i])
+			missingKeys = append(missingKeys, key)
+		}
+	}
+	if len(missingNames) == 0 {
+		return groupIDs, nil
+	}
+
+	query := `
+        INSERT INTO groups (name, name_key) SELECT * FROM unnest($1::text[], $2::text[])
+        ON CONFLICT (name) DO UPDATE SET name_key = EXCLUDED.name_key
+        RETURNING id, name_key
+    `
+	rows, err := q.QueryContext(ctx, query, pq.Array(missingNames), pq.Array(missingKeys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert groups: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		groupIDs[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return groupIDs, nil
+}
+
+// lookUpGroupKeys maps each of keys to the group whose name key it is, or
+// failing that to the group it is an alias key of. Rows not yet backfilled
+// are still found through their exact name.
+func (r *SongRepository) lookUpGroupKeys(ctx context.Context, q dbtx, names, keys []string) (map[string]int, error) {
+	query := `
+        SELECT DISTINCT ON (k.group_key) k.group_key, m.id
+        FROM unnest($1::text[], $2::text[]) AS k(group_name, group_key)
+        JOIN (
+            SELECT name, name_key, id, 0 AS rank FROM groups
+            UNION ALL
+            SELECT alias, alias_key, group_id, 1 FROM group_aliases
+        ) m ON m.name_key = k.group_key OR m.name = k.group_name
+        ORDER BY k.group_key, m.rank, m.id
+    `
+	rows, err := q.QueryContext(ctx, query, pq.Array(names), pq.Array(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up groups: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	groupIDs := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var id int
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		groupIDs[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return groupIDs, nil
+}
+
+// insertChunk inserts songs[indexes] with one multi-row INSERT, records
+// their first revision and stores the new IDs in outcomes.
+func (r *SongRepository) insertChunk(ctx context.Context, q dbtx, songs []models.Song, indexes []int, groupIDs map[string]int, outcomes []BulkOutcome) error {
+	var (
+		groups       = make([]int64, len(indexes))
+		titles       = make([]string, len(indexes))
+		titleKeys    = make([]string, len(indexes))
+		releaseDates = make([]string, len(indexes))
+		lyrics       = make([]string, len(indexes))
+		links        = make([]string, len(indexes))
+		positions    = make(map[groupSongKey]int, len(indexes))
+	)
+	for i, index := range indexes {
+		song := songs[index]
+		groups[i] = int64(groupIDs[normalize.Key(song.Group)])
+		titles[i] = song.Song
+		titleKeys[i] = normalize.Key(song.Song)
+		releaseDates[i] = "0001-01-01"
+		if song.ReleaseDate != nil {
+			releaseDates[i] = song.ReleaseDate.Format("2006-01-02")
+		}
+		lyrics[i] = song.Lyrics
+		links[i] = song.Link
+		positions[groupSongKey{int(groups[i]), song.Song}] = index
+	}
+
+	query := `
+        INSERT INTO songs (group_id, song, title_key, release_date, lyrics, link)
+        SELECT * FROM unnest($1::int[], $2::text[], $3::text[], $4::date[], $5::text[], $6::text[])
+        RETURNING id, group_id, song
+    `
+	rows, err := q.QueryContext(ctx, query, pq.Array(groups), pq.Array(titles), pq.Array(titleKeys), pq.Array(releaseDates), pq.Array(lyrics), pq.Array(links))
+	if err != nil {
+		return fmt.Errorf("failed to insert songs: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, len(indexes))
+	for rows.Next() {
+		var id int
+		var key groupSongKey
+		if err := rows.Scan(&id, &key.groupID, &key.song); err != nil {
+			return fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		outcomes[positions[key]].ID = id
+		ids = append(ids, int64(id))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+
+	query = `
+        INSERT INTO song_revisions (song_id, revision, group_name, song, release_date, lyrics, link, changed_fields)
+        SELECT s.id, 1, g.name, s.song, s.release_date, s.lyrics, s.link, $2
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        WHERE s.id = ANY($1)
+    `
+	if _, err := q.ExecContext(ctx, query, pq.Array(ids), pq.Array(revisionFields)); err != nil {
+		return fmt.Errorf("failed to insert revisions: %w", withContextError(ctx, err))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"song-library/pkg/normalize"
+	"strings"
+	"time"
+)
+
+// SongChanges is a partial update of a song. Nil fields are left untouched;
+// a zero ReleaseDate clears the release date.
+type SongChanges struct {
+	Group       *string
+	Song        *string
+	ReleaseDate *time.Time
+	Lyrics      *string
+	Link        *string
+}
+
+func (c SongChanges) IsEmpty() bool {
+	return c.Group == nil && c.Song == nil && c.ReleaseDate == nil && c.Lyrics == nil && c.Link == nil
+}
+
+// PatchSong writes only the columns set in changes. Like UpdateSong, a
+// non-zero ifVersion guards the write against concurrent modifications.
+func (r *SongRepository) PatchSong(ctx context.Context, id int, changes SongChanges, ifVersion int) error {
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	ctx, cancel := r.Timeouts.apply(ctx, "update_song")
+	defer cancel()
+
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		var b queryBuilder
+		var sets []string
+		if changes.Group != nil || changes.Song != nil {
+			groupID, err := r.checkPatchedDuplicate(ctx, tx, id, changes)
+			if err != nil {
+				return err
+			}
+			if changes.Group != nil {
+				sets = append(sets, "group_id = "+b.arg(groupID))
+			}
+		}
+		if changes.Song != nil {
+			sets = append(sets, "song = "+b.arg(*changes.Song))
+			sets = append(sets, "title_key = "+b.arg(normalize.Key(*changes.Song)))
+		}
+		if changes.ReleaseDate != nil {
+			sets = append(sets, "release_date = "+b.arg(*changes.ReleaseDate))
+		}
+		if changes.Lyrics != nil {
+			sets = append(sets, "lyrics = "+b.arg(*changes.Lyrics))
+		}
+		if changes.Link != nil {
+			sets = append(sets, "link = "+b.arg(*changes.Link))
+		}
+
+		b.where("id = " + b.arg(id))
+		b.where("deleted_at IS NULL")
+		if ifVersion != 0 {
+			b.where("version = " + b.arg(ifVersion))
+		}
+
+		query := `
+        UPDATE songs
+        SET ` + strings.Join(sets, ", ") + `, version = version + 1, updated_at = CURRENT_TIMESTAMP
+        ` + b.whereClause()
+		result, err := tx.ExecContext(ctx, query, b.args...)
+		if err != nil {
+			return fmt.Errorf("failed to patch song: %w", withContextError(ctx, err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		if affected == 0 {
+			return versionMiss(ctx, tx, id)
+		}
+		return r.recordRevision(ctx, tx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully patched song with ID %d", id)
+	return nil
+}
+
+// checkPatchedDuplicate resolves the group a patch moves song id to and makes
+// sure the patched song does not duplicate another. It returns the group ID
+// the song ends up in; a missing song is left for the UPDATE to report.
+func (r *SongRepository) checkPatchedDuplicate(ctx context.Context, q dbtx, id int, changes SongChanges) (int, error) {
+	var groupID int
+	var title string
+	err := q.QueryRowContext(ctx, `SELECT group_id, song FROM songs WHERE id = $1`, id).Scan(&groupID, &title)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return 0, fmt.Errorf("failed to look up song: %w", withContextError(ctx, err))
+	}
+
+	if changes.Group != nil {
+		if groupID, err = r.getOrCreateGroupID(ctx, q, *changes.Group); err != nil {
+			return 0, fmt.Errorf("failed to get or create group ID: %w", err)
+		}
+	}
+	if changes.Song != nil {
+		title = *changes.Song
+	}
+	if err := checkDuplicateSong(ctx, q, groupID, title, id); err != nil {
+		return 0, err
+	}
+	return groupID, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"song-library/internal/models"
+)
+
+// exportFetchSize is the number of rows pulled from the export cursor per
+// round trip.
+const exportFetchSize = 500
+
+// StreamSongs calls fn for every song matching filter, in sort order. Rows are
+// fetched in batches from a server-side cursor inside a read-only snapshot, so
+// memory use does not grow with the catalog. With omitLyrics the lyrics are
+// never read from the table. An error from fn stops the stream and is returned.
+func (r *SongRepository) StreamSongs(ctx context.Context, filter SongFilter, sort SongSort, omitLyrics bool, fn func(song models.Song) error) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "export_songs")
+	defer cancel()
+
+	columns := songColumns
+	if omitLyrics {
+		columns = songColumnsWithoutLyrics
+	}
+
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", withContextError(ctx, err))
+	}
+	// The transaction only reads, so rolling back is how it ends.
+	defer tx.Rollback()
+
+	var b queryBuilder
+	filter.apply(&b)
+	query := `
+        DECLARE song_export NO SCROLL CURSOR FOR
+        SELECT ` + columns + `
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        ` + b.whereClause() + `
+        ORDER BY ` + sort.orderBy(false)
+	if _, err := tx.ExecContext(ctx, query, b.args...); err != nil {
+		return fmt.Errorf("error declaring export cursor: %w", withContextError(ctx, err))
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM song_export", exportFetchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("error fetching from export cursor: %w", withContextError(ctx, err))
+		}
+		songs, err := scanSongs(ctx, rows)
+		if err != nil {
+			return err
+		}
+		for _, song := range songs {
+			if err := fn(song); err != nil {
+				return err
+			}
+		}
+		if len(songs) < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"song-library/internal/models"
+	"strings"
+	"time"
+)
+
+// SongFilter narrows the songs returned by GetSongs, GetSongsByKeyset and
+// CountSongs. Zero-valued fields do not restrict the result. Songs without a
+// release date (stored as 0001-01-01) never match a release date filter.
+type SongFilter struct {
+	Group string
+	Song  string
+	// GroupID restricts the result to the songs of one group.
+	GroupID int
+
+	ReleaseFrom *time.Time
+	ReleaseTo   *time.Time
+	Year        int
+	// Decade is the first year of the decade, e.g. 1990.
+	Decade int
+
+	HasLyrics *bool
+	HasLink   *bool
+	// LinkHost matches the host of the link or any of its subdomains.
+	LinkHost string
+
+	CreatedAfter *time.Time
+	UpdatedAfter *time.Time
+
+	// Trashed selects soft-deleted songs instead of live ones.
+	Trashed bool
+
+	// aliasedGroups holds the IDs of groups with an alias matching Group. It
+	// is filled in by MemorySongRepository; SQL queries match aliases
+	// themselves.
+	aliasedGroups map[int]bool
+}
+
+// queryBuilder collects WHERE conditions and their positional arguments.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// arg registers a query argument and returns its $n placeholder.
+func (b *queryBuilder) arg(value any) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *queryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// songCondition is one filter criterion: apply adds its SQL to a query and
+// match evaluates it against an in-memory song.
+type songCondition struct {
+	apply func(b *queryBuilder)
+	match func(song models.Song) bool
+}
+
+const linkHostExpr = `lower(substring(COALESCE(s.link, '') from '^[A-Za-z][A-Za-z0-9+.-]*://([^/:?#@]+)'))`
+
+// conditions returns the criteria selected by the filter. The trash and the
+// group and song ILIKE conditions are always present; the group condition
+// also matches the group's aliases.
+func (f SongFilter) conditions() []songCondition {
+	conditions := []songCondition{
+		{
+			apply: func(b *queryBuilder) {
+				if f.Trashed {
+					b.where("s.deleted_at IS NOT NULL")
+				} else {
+					b.where("s.deleted_at IS NULL")
+				}
+			},
+			match: func(song models.Song) bool { return (song.DeletedAt != nil) == f.Trashed },
+		},
+		{
+			apply: func(b *queryBuilder) {
+				pattern := b.arg("%" + f.Group + "%")
+				if f.Group == "" {
+					b.where("g.name ILIKE " + pattern)
+					return
+				}
+				b.where("(g.name ILIKE " + pattern + " OR EXISTS (SELECT 1 FROM group_aliases ga WHERE ga.group_id = g.id AND ga.alias ILIKE " + pattern + "))")
+			},
+			match: func(song models.Song) bool {
+				return ilike(song.Group, "%"+f.Group+"%") || f.aliasedGroups[song.GroupID]
+			},
+		},
+		{
+			apply: func(b *queryBuilder) { b.where("s.song ILIKE " + b.arg("%"+f.Song+"%")) },
+			match: func(song models.Song) bool { return ilike(song.Song, "%"+f.Song+"%") },
+		},
+	}
+
+	if f.GroupID != 0 {
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) { b.where("s.group_id = " + b.arg(f.GroupID)) },
+			match: func(song models.Song) bool { return song.GroupID == f.GroupID },
+		})
+	}
+
+	from, to := f.releaseRange()
+	if from != nil {
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) { b.where("s.release_date >= " + b.arg(from.Format("2006-01-02")) + "::date") },
+			match: func(song models.Song) bool { return !isZeroDate(song.ReleaseDate) && !song.ReleaseDate.Before(*from) },
+		})
+	}
+	if to != nil {
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) {
+				b.where("s.release_date <= " + b.arg(to.Format("2006-01-02")) + "::date AND s.release_date > DATE '0001-01-01'")
+			},
+			match: func(song models.Song) bool { return !isZeroDate(song.ReleaseDate) && !song.ReleaseDate.After(*to) },
+		})
+	}
+
+	if f.HasLyrics != nil {
+		conditions = append(conditions, presenceCondition("s.lyrics", *f.HasLyrics, func(song models.Song) string { return song.Lyrics }))
+	}
+	if f.HasLink != nil {
+		conditions = append(conditions, presenceCondition("s.link", *f.HasLink, func(song models.Song) string { return song.Link }))
+	}
+	if f.LinkHost != "" {
+		host := strings.ToLower(f.LinkHost)
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) {
+				// A suffix comparison rather than LIKE, so % and _ in host
+				// are not wildcards.
+				hostArg := b.arg(host) + "::text"
+				b.where("(" + linkHostExpr + " = " + hostArg + " OR right(" + linkHostExpr + ", length(" + hostArg + ") + 1) = '.' || " + hostArg + ")")
+			},
+			match: func(song models.Song) bool {
+				linkHost := linkHostOf(song.Link)
+				return linkHost == host || strings.HasSuffix(linkHost, "."+host)
+			},
+		})
+	}
+
+	if f.CreatedAfter != nil {
+		createdAfter := *f.CreatedAfter
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) { b.where("s.created_at > " + b.arg(createdAfter)) },
+			match: func(song models.Song) bool { return song.CreatedAt.After(createdAfter) },
+		})
+	}
+	if f.UpdatedAfter != nil {
+		updatedAfter := *f.UpdatedAfter
+		conditions = append(conditions, songCondition{
+			apply: func(b *queryBuilder) { b.where("s.updated_at > " + b.arg(updatedAfter)) },
+			match: func(song models.Song) bool { return song.UpdatedAt.After(updatedAfter) },
+		})
+	}
+	return conditions
+}
+
+// releaseRange folds ReleaseFrom, ReleaseTo, Year and Decade into the
+// narrowest inclusive date range.
+func (f SongFilter) releaseRange() (from, to *time.Time) {
+	narrow := func(lower, upper *time.Time) {
+		if lower != nil && (from == nil || lower.After(*from)) {
+			from = lower
+		}
+		if upper != nil && (to == nil || upper.Before(*to)) {
+			to = upper
+		}
+	}
+
+	narrow(f.ReleaseFrom, f.ReleaseTo)
+	if f.Year != 0 {
+		narrow(yearStart(f.Year), yearEnd(f.Year))
+	}
+	if f.Decade != 0 {
+		narrow(yearStart(f.Decade), yearEnd(f.Decade+9))
+	}
+	return from, to
+}
+
+func yearStart(year int) *time.Time {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return &start
+}
+
+func yearEnd(year int) *time.Time {
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	return &end
+}
+
+func presenceCondition(column string, present bool, value func(song models.Song) string) songCondition {
+	operator := "="
+	if present {
+		operator = "<>"
+	}
+	return songCondition{
+		apply: func(b *queryBuilder) { b.where("COALESCE(" + column + ", '') " + operator + " ''") },
+		match: func(song models.Song) bool { return (value(song) != "") == present },
+	}
+}
+
+func linkHostOf(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+func (f SongFilter) apply(b *queryBuilder) {
+	for _, condition := range f.conditions() {
+		condition.apply(b)
+	}
+}
+
+// matcher is the in-memory counterpart of apply.
+func (f SongFilter) matcher() func(song models.Song) bool {
+	conditions := f.conditions()
+	return func(song models.Song) bool {
+		for _, condition := range conditions {
+			if !condition.match(song) {
+				return false
+			}
+		}
+		return true
+	}
+}
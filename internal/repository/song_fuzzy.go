@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"song-library/internal/models"
+	"strconv"
+)
+
+// FuzzyMatcher is implemented by stores that support typo-tolerant matching
+// of group and song names.
+type FuzzyMatcher interface {
+	FuzzySongs(ctx context.Context, query FuzzyQuery) ([]models.ScoredSong, error)
+	SuggestSongFilter(ctx context.Context, filter SongFilter, threshold float64) (*SongFilter, error)
+}
+
+// FuzzyQuery matches Group and Song by trigram word similarity. At least one
+// of them must be set; Threshold is the minimum similarity in (0, 1]. Filter
+// narrows the matches further, its Group and Song being ignored, and a
+// non-empty Sort orders them instead of their similarity.
+type FuzzyQuery struct {
+	Group     string
+	Song      string
+	Threshold float64
+	Filter    SongFilter
+	Sort      SongSort
+	Page      int
+	Limit     int
+}
+
+func (r *SongRepository) FuzzySongs(ctx context.Context, fuzzy FuzzyQuery) ([]models.ScoredSong, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_songs")
+	defer cancel()
+
+	if fuzzy.Page < 1 || fuzzy.Limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+	if fuzzy.Group == "" && fuzzy.Song == "" {
+		return nil, fmt.Errorf("fuzzy match needs a group or song")
+	}
+
+	// <% uses the trigram GIN indexes; word_similarity provides the score.
+	var b queryBuilder
+	var scores []string
+	filter := fuzzy.Filter
+	filter.Group, filter.Song, filter.Trashed = "", "", false
+	filter.apply(&b)
+	if fuzzy.Group != "" {
+		group := b.arg(fuzzy.Group)
+		b.where(group + " <% g.name")
+		scores = append(scores, "word_similarity("+group+", g.name)")
+	}
+	if fuzzy.Song != "" {
+		song := b.arg(fuzzy.Song)
+		b.where(song + " <% s.song")
+		scores = append(scores, "word_similarity("+song+", s.song)")
+	}
+	score := scores[0]
+	if len(scores) == 2 {
+		score = "(" + scores[0] + " + " + scores[1] + ") / 2"
+	}
+	orderBy := "score DESC, s.id"
+	if len(fuzzy.Sort) > 0 {
+		orderBy = fuzzy.Sort.orderBy(false)
+	}
+	query := `
+        SELECT ` + songColumns + `, ` + score + ` AS score
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        ` + b.whereClause() + `
+        ORDER BY ` + orderBy + `
+        LIMIT ` + b.arg(fuzzy.Limit) + ` OFFSET ` + b.arg((fuzzy.Page-1)*fuzzy.Limit)
+
+	var songs []models.ScoredSong
+	err := r.withTrigramThreshold(ctx, fuzzy.Threshold, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, b.args...)
+		if err != nil {
+			return fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var song models.ScoredSong
+			if err := rows.Scan(append(songFields(&song.Song), &song.Score)...); err != nil {
+				return fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+			}
+			songs = append(songs, song)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+		}
+		return nil
+	})
+	return songs, err
+}
+
+// SuggestSongFilter returns the filter with Group and Song replaced by the
+// most similar existing names, or nil when nothing close enough exists.
+func (r *SongRepository) SuggestSongFilter(ctx context.Context, filter SongFilter, threshold float64) (*SongFilter, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_songs")
+	defer cancel()
+
+	suggestion := filter
+	found := false
+	err := r.withTrigramThreshold(ctx, threshold, func(tx *sql.Tx) error {
+		lookups := []struct {
+			value *string
+			query string
+		}{
+			{&suggestion.Group, `SELECT name FROM groups WHERE $1 <% name ORDER BY word_similarity($1, name) DESC, id LIMIT 1`},
+			{&suggestion.Song, `SELECT song FROM songs WHERE $1 <% song AND deleted_at IS NULL ORDER BY word_similarity($1, song) DESC, id LIMIT 1`},
+		}
+		for _, lookup := range lookups {
+			if *lookup.value == "" {
+				continue
+			}
+			var name string
+			err := tx.QueryRowContext(ctx, lookup.query, *lookup.value).Scan(&name)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error fetching suggestion: %w", withContextError(ctx, err))
+			}
+			*lookup.value = name
+			found = true
+		}
+		return nil
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// withTrigramThreshold runs fn in a read-only transaction with the word
+// similarity threshold used by the <% operator set to threshold.
+func (r *SongRepository) withTrigramThreshold(ctx context.Context, threshold float64, fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", withContextError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	setting := strconv.FormatFloat(threshold, 'f', -1, 64)
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, setting); err != nil {
+		return fmt.Errorf("failed to set similarity threshold: %w", withContextError(ctx, err))
+	}
+	return fn(tx)
+}
@@ -1,132 +1,540 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"song-library/internal/models"
+	"song-library/pkg/normalize"
+	"time"
 )
 
 type SongRepository struct {
-	DB *sql.DB
+	DB         *sql.DB
+	UnitOfWork *UnitOfWork
+	Timeouts   QueryTimeouts
 }
 
-func NewSongRepository(db *sql.DB) *SongRepository {
-	return &SongRepository{DB: db}
+func NewSongRepository(db *sql.DB, timeouts QueryTimeouts) *SongRepository {
+	return &SongRepository{DB: db, UnitOfWork: NewUnitOfWork(db), Timeouts: timeouts}
 }
 
-func (r *SongRepository) GetSongs(group, song string, page, limit int) ([]models.Song, error) {
+// songColumns is the select list read by songFields. songColumnsWithoutLyrics
+// is the same list with empty lyrics, for reads that must not load them.
+const (
+	songColumns              = songColumnsBeforeLyrics + `COALESCE(s.lyrics, '')` + songColumnsAfterLyrics
+	songColumnsWithoutLyrics = songColumnsBeforeLyrics + `''` + songColumnsAfterLyrics
+
+	songColumnsBeforeLyrics = `s.id, g.name, s.song, s.release_date, `
+	songColumnsAfterLyrics  = `, COALESCE(s.link, ''),
+        COALESCE(s.created_at, TIMESTAMP 'epoch'), COALESCE(s.updated_at, TIMESTAMP 'epoch'), s.deleted_at, s.version`
+)
+
+func songFields(song *models.Song) []any {
+	return []any{&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Lyrics, &song.Link, &song.CreatedAt, &song.UpdatedAt, &song.DeletedAt, &song.Version}
+}
+
+func (r *SongRepository) GetSongs(ctx context.Context, filter SongFilter, sort SongSort, page, limit int) ([]models.Song, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_songs")
+	defer cancel()
+
 	if page < 1 || limit < 1 {
 		return nil, fmt.Errorf("page and limit must be greater than 0")
 	}
 	offset := (page - 1) * limit
+
+	var b queryBuilder
+	filter.apply(&b)
 	query := `
-        SELECT s.id, g.name, s.song, s.release_date, s.lyrics, s.link
+        SELECT ` + songColumns + `
         FROM songs s
         JOIN groups g ON s.group_id = g.id
-        WHERE g.name ILIKE $1 AND s.song ILIKE $2
-        ORDER BY s.id
-        LIMIT $3 OFFSET $4
-    `
-	rows, err := r.DB.Query(query, "%"+group+"%", "%"+song+"%", limit, offset)
+        ` + b.whereClause() + `
+        ORDER BY ` + sort.orderBy(false) + `
+        LIMIT ` + b.arg(limit) + ` OFFSET ` + b.arg(offset)
+	rows, err := r.DB.QueryContext(ctx, query, b.args...)
 	if err != nil {
-		return nil, fmt.Errorf("error executing query: %w", err)
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
 	}
-	defer rows.Close()
-	var songs []models.Song
-	for rows.Next() {
-		var song models.Song
-		if err := rows.Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Lyrics, &song.Link); err != nil {
-			return nil, fmt.Errorf("error scanning row: %w", err)
-		}
-		songs = append(songs, song)
+	return scanSongs(ctx, rows)
+}
+
+func (r *SongRepository) CountSongs(ctx context.Context, filter SongFilter) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "count_songs")
+	defer cancel()
+
+	var b queryBuilder
+	filter.apply(&b)
+	query := `
+        SELECT COUNT(*)
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        ` + b.whereClause()
+	var total int
+	if err := r.DB.QueryRowContext(ctx, query, b.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("error counting songs: %w", withContextError(ctx, err))
 	}
-	return songs, nil
+	return total, nil
 }
 
-func (r *SongRepository) getOrCreateGroupID(groupName string) (int, error) {
-	var groupID int
-	query := `SELECT id FROM groups WHERE name = $1`
-	err := r.DB.QueryRow(query, groupName).Scan(&groupID)
-	if err == sql.ErrNoRows {
-		insertQuery := `INSERT INTO groups (name) VALUES ($1) RETURNING id`
-		err = r.DB.QueryRow(insertQuery, groupName).Scan(&groupID)
+func (r *SongRepository) GetSongsByKeyset(ctx context.Context, filter SongFilter, sort SongSort, keyset Keyset, limit int) (*KeysetPage, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_songs")
+	defer cancel()
+
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be greater than 0")
+	}
+
+	var b queryBuilder
+	filter.apply(&b)
+	if !keyset.isStart() {
+		condition, err := sort.keysetCondition(&b, keyset)
 		if err != nil {
-			return 0, fmt.Errorf("failed to create group: %w", err)
+			return nil, err
 		}
-	} else if err != nil {
-		return 0, fmt.Errorf("failed to fetch group ID: %w", err)
+		b.where(condition)
 	}
-	return groupID, nil
+	query := `
+        SELECT ` + songColumns + `
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        ` + b.whereClause() + `
+        ORDER BY ` + sort.orderBy(keyset.Backward) + `
+        LIMIT ` + b.arg(limit+1)
+	rows, err := r.DB.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	songs, err := scanSongs(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	return newKeysetPage(songs, keyset, limit), nil
 }
 
-func (r *SongRepository) AddSong(song models.Song) error {
-	groupID, err := r.getOrCreateGroupID(song.Group)
+// getOrCreateGroupID resolves a group name to the group with the same name
+// key, or to the group it is an alias of, and otherwise upserts the group, so
+// concurrent writers for a new group never race on the UNIQUE name constraint.
+func (r *SongRepository) getOrCreateGroupID(ctx context.Context, q dbtx, groupName string) (int, error) {
+	key := normalize.Key(groupName)
+	groupIDs, err := r.lookUpGroupKeys(ctx, q, []string{groupName}, []string{key})
 	if err != nil {
-		return fmt.Errorf("failed to get or create group ID: %w", err)
+		return 0, err
+	}
+	if groupID, ok := groupIDs[key]; ok {
+		return groupID, nil
 	}
 
+	var groupID int
 	query := `
-		INSERT INTO songs (group_id, song, release_date, lyrics, link) 
-		VALUES ($1, $2, $3, $4, $5)`
-	_, err = r.DB.Exec(query, groupID, song.Song, song.ReleaseDate, song.Lyrics, song.Link)
+        INSERT INTO groups (name, name_key) VALUES ($1, $2)
+        ON CONFLICT (name) DO UPDATE SET name_key = EXCLUDED.name_key
+        RETURNING id
+    `
+	if err := q.QueryRowContext(ctx, query, groupName, key).Scan(&groupID); err != nil {
+		return 0, fmt.Errorf("failed to upsert group: %w", withContextError(ctx, err))
+	}
+	return groupID, nil
+}
+
+// AddSong inserts a song and returns the stored row, filed under the group
+// its name resolved to.
+func (r *SongRepository) AddSong(ctx context.Context, song models.Song) (*models.Song, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "add_song")
+	defer cancel()
+
+	var added models.Song
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		groupID, err := r.getOrCreateGroupID(ctx, tx, song.Group)
+		if err != nil {
+			return fmt.Errorf("failed to get or create group ID: %w", err)
+		}
+		if err := checkDuplicateSong(ctx, tx, groupID, song.Song, 0); err != nil {
+			return err
+		}
+
+		query := `
+		WITH s AS (
+			INSERT INTO songs (group_id, song, title_key, release_date, lyrics, link)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *
+		)
+		SELECT ` + songColumns + `
+		FROM s
+		JOIN groups g ON s.group_id = g.id`
+		if err := tx.QueryRowContext(ctx, query, groupID, song.Song, normalize.Key(song.Song), song.ReleaseDate, song.Lyrics, song.Link).Scan(songFields(&added)...); err != nil {
+			return fmt.Errorf("failed to insert song: %w", withContextError(ctx, err))
+		}
+		return r.recordRevision(ctx, tx, added.ID)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to insert song: %w", err)
+		return nil, err
 	}
 
 	log.Printf("Successfully added song %q by group %q", song.Song, song.Group)
-	return nil
+	return &added, nil
 }
 
-func (r *SongRepository) GetSongByID(songID string) (*models.Song, error) {
+// UpsertSong adds a song or, when its group already has a live song with the
+// same title key, replaces that song instead, in a single statement. created
+// reports which happened.
+func (r *SongRepository) UpsertSong(ctx context.Context, song models.Song) (*models.Song, bool, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "add_song")
+	defer cancel()
+
+	var stored models.Song
+	var created bool
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		groupID, err := r.getOrCreateGroupID(ctx, tx, song.Group)
+		if err != nil {
+			return fmt.Errorf("failed to get or create group ID: %w", err)
+		}
+
+		query := `
+		WITH s AS (
+			INSERT INTO songs (group_id, song, title_key, release_date, lyrics, link)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (group_id, title_key) WHERE deleted_at IS NULL DO UPDATE
+			SET song = EXCLUDED.song, release_date = EXCLUDED.release_date, lyrics = EXCLUDED.lyrics, link = EXCLUDED.link,
+			    version = songs.version + 1, updated_at = CURRENT_TIMESTAMP
+			RETURNING *, xmax = 0 AS inserted
+		)
+		SELECT ` + songColumns + `, s.inserted
+		FROM s
+		JOIN groups g ON s.group_id = g.id`
+		fields := append(songFields(&stored), &created)
+		if err := tx.QueryRowContext(ctx, query, groupID, song.Song, normalize.Key(song.Song), song.ReleaseDate, song.Lyrics, song.Link).Scan(fields...); err != nil {
+			return fmt.Errorf("failed to upsert song: %w", withContextError(ctx, err))
+		}
+		if !created {
+			if stored.Albums, err = songAlbums(ctx, tx, stored.ID); err != nil {
+				return err
+			}
+		}
+		return r.recordRevision(ctx, tx, stored.ID)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	log.Printf("Successfully upserted song %q by group %q", song.Song, song.Group)
+	return &stored, created, nil
+}
+
+func (r *SongRepository) GetSongByID(ctx context.Context, songID string) (*models.Song, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_song")
+	defer cancel()
+
 	query := `
-        SELECT s.id, g.name, s.song, s.release_date, s.lyrics, s.link
+        SELECT ` + songColumns + `
         FROM songs s
         JOIN groups g ON s.group_id = g.id
-        WHERE s.id = $1
+        WHERE s.id = $1 AND s.deleted_at IS NULL
     `
 	var song models.Song
-	err := r.DB.QueryRow(query, songID).Scan(&song.ID, &song.Group, &song.Song, &song.ReleaseDate, &song.Lyrics, &song.Link)
+	err := r.DB.QueryRowContext(ctx, query, songID).Scan(songFields(&song)...)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSongNotFound
+		}
+		return nil, fmt.Errorf("error fetching song: %w", withContextError(ctx, err))
+	}
+	if song.Albums, err = songAlbums(ctx, r.DB, song.ID); err != nil {
 		return nil, err
 	}
+	return &song, nil
+}
+
+func (r *SongRepository) UpdateSong(ctx context.Context, id int, song models.Song, ifVersion int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "update_song")
+	defer cancel()
 
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		return r.updateSong(ctx, tx, id, song, ifVersion)
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("song not found")
-		}
-		return nil, fmt.Errorf("error fetching song: %w", err)
+		return err
 	}
-	return &song, nil
+
+	log.Printf("Successfully updated song with ID %d", id)
+	return nil
 }
 
-func (r *SongRepository) UpdateSong(id int, song models.Song) error {
-	groupID, err := r.getOrCreateGroupID(song.Group)
+// updateSong replaces a song within q. Besides UpdateSong, AddSongs uses it
+// to overwrite the songs a batch duplicates.
+func (r *SongRepository) updateSong(ctx context.Context, q dbtx, id int, song models.Song, ifVersion int) error {
+	groupID, err := r.getOrCreateGroupID(ctx, q, song.Group)
 	if err != nil {
 		return fmt.Errorf("failed to get or create group ID: %w", err)
 	}
 
+	if err := checkDuplicateSong(ctx, q, groupID, song.Song, id); err != nil {
+		return err
+	}
+
 	query := `
         UPDATE songs
-        SET group_id = $1, song = $2, release_date = $3, lyrics = $4, link = $5, updated_at = CURRENT_TIMESTAMP
-        WHERE id = $6
+        SET group_id = $1, song = $2, title_key = $3, release_date = $4, lyrics = $5, link = $6,
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $7 AND deleted_at IS NULL AND ($8::int = 0 OR version = $8)
     `
-	_, err = r.DB.Exec(query, groupID, song.Song, song.ReleaseDate, song.Lyrics, song.Link, id)
+	result, err := q.ExecContext(ctx, query, groupID, song.Song, normalize.Key(song.Song), song.ReleaseDate, song.Lyrics, song.Link, id, ifVersion)
 	if err != nil {
-		return fmt.Errorf("failed to update song: %w", err)
+		return fmt.Errorf("failed to update song: %w", withContextError(ctx, err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		if ifVersion != 0 {
+			return versionMiss(ctx, q, id)
+		}
+		// Failing also rolls back a group created for the song above.
+		return ErrSongNotFound
 	}
+	return r.recordRevision(ctx, q, id)
+}
 
-	log.Printf("Successfully updated song with ID %d", id)
+func (r *SongRepository) DeleteSong(ctx context.Context, id int, ifVersion int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "delete_song")
+	defer cancel()
+
+	query := `
+        UPDATE songs SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+        WHERE id = $1 AND deleted_at IS NULL AND ($2::int = 0 OR version = $2)
+    `
+	result, err := r.DB.ExecContext(ctx, query, id, ifVersion)
+	if err != nil {
+		return fmt.Errorf("failed to delete song: %w", withContextError(ctx, err))
+	}
+	if err := expectOneRow(result); err != nil {
+		if ifVersion != 0 {
+			return versionMiss(ctx, r.DB, id)
+		}
+		return err
+	}
+
+	log.Printf("Successfully moved song with ID %d to trash", id)
+	return nil
+}
+
+// RestoreSong moves a song out of the trash. It fails with a
+// *DuplicateSongError if its group has since gained a live song with the same
+// title.
+func (r *SongRepository) RestoreSong(ctx context.Context, id int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "restore_song")
+	defer cancel()
+
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		var groupID int
+		var title string
+		query := `SELECT group_id, song FROM songs WHERE id = $1 AND deleted_at IS NOT NULL`
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&groupID, &title); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrSongNotFound
+			}
+			return fmt.Errorf("failed to look up song: %w", withContextError(ctx, err))
+		}
+		if err := checkDuplicateSong(ctx, tx, groupID, title, id); err != nil {
+			return err
+		}
+
+		query = `
+            UPDATE songs SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+            WHERE id = $1 AND deleted_at IS NOT NULL
+        `
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return fmt.Errorf("failed to restore song: %w", withContextError(ctx, err))
+		}
+		return expectOneRow(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully restored song with ID %d", id)
+	return nil
+}
+
+// PurgeSong permanently deletes a song that is already in the trash.
+func (r *SongRepository) PurgeSong(ctx context.Context, id int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "delete_song")
+	defer cancel()
+
+	query := `DELETE FROM songs WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge song: %w", withContextError(ctx, err))
+	}
+	if err := expectOneRow(result); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully purged song with ID %d", id)
 	return nil
 }
 
-func (r *SongRepository) DeleteSong(id int) error {
-	query := `DELETE FROM songs WHERE id = $1`
-	_, err := r.DB.Exec(query, id)
+// PurgeDeletedSongs permanently deletes songs trashed before cutoff and
+// returns how many were removed.
+func (r *SongRepository) PurgeDeletedSongs(ctx context.Context, cutoff time.Time) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "purge_songs")
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM songs WHERE deleted_at < $1`, cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to delete song: %w", err)
+		return 0, fmt.Errorf("failed to purge songs: %w", withContextError(ctx, err))
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return int(purged), nil
+}
+
+// songExists reports whether a live song with the given ID exists.
+func songExists(ctx context.Context, q dbtx, id int) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error fetching song: %w", withContextError(ctx, err))
+	}
+	return exists, nil
+}
+
+// versionMiss explains why a write guarded by a version matched no row: the
+// song is either gone or has been modified since that version was read.
+func versionMiss(ctx context.Context, q dbtx, id int) error {
+	exists, err := songExists(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrSongNotFound
+}
+
+// checkDuplicateSong returns a *DuplicateSongError when a live song of group
+// groupID other than exceptID has the title key of title. Songs written
+// before title keys existed are compared by exact title until backfilled.
+func checkDuplicateSong(ctx context.Context, q dbtx, groupID int, title string, exceptID int) error {
+	query := `
+        SELECT id FROM songs
+        WHERE group_id = $1 AND (title_key = $2 OR song = $3) AND id <> $4 AND deleted_at IS NULL
+        ORDER BY id
+        LIMIT 1
+    `
+	var existingID int
+	err := q.QueryRowContext(ctx, query, groupID, normalize.Key(title), title, exceptID).Scan(&existingID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to look up duplicate songs: %w", withContextError(ctx, err))
 	}
+	return &DuplicateSongError{ExistingID: existingID}
+}
 
-	log.Printf("Successfully deleted song with ID %d", id)
+// expectOneRow maps an UPDATE or DELETE that matched nothing to ErrSongNotFound.
+func expectOneRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrSongNotFound
+	}
 	return nil
 }
+
+// incompleteSongCondition matches rows missing lyrics, link or a release date.
+// A zero release date is stored as 0001-01-01 when the request omits it.
+const incompleteSongCondition = `(COALESCE(s.lyrics, '') = '' OR COALESCE(s.link, '') = ''
+        OR s.release_date IS NULL OR s.release_date = '0001-01-01')`
+
+func (r *SongRepository) GetIncompleteSongs(ctx context.Context, afterID, limit int) ([]models.Song, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_incomplete_songs")
+	defer cancel()
+
+	query := `
+        SELECT ` + songColumns + `
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        WHERE s.id > $1 AND s.deleted_at IS NULL AND ` + incompleteSongCondition + `
+        ORDER BY s.id
+        LIMIT $2
+    `
+	rows, err := r.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	return scanSongs(ctx, rows)
+}
+
+// FillMissingFields writes lyrics, link and release date only where the stored
+// value is still empty and no revision since the song was created changed it,
+// so fields edited or cleared in the meantime are left untouched. It reports
+// whether any column was changed.
+func (r *SongRepository) FillMissingFields(ctx context.Context, id int, song models.Song) (bool, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "fill_missing_fields")
+	defer cancel()
+
+	query := `
+        UPDATE songs
+        SET lyrics = CASE WHEN COALESCE(lyrics, '') = '' THEN $2 ELSE lyrics END,
+            link = CASE WHEN COALESCE(link, '') = '' THEN $3 ELSE link END,
+            release_date = CASE WHEN release_date IS NULL OR release_date = '0001-01-01'
+                THEN COALESCE($4, release_date) ELSE release_date END,
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND deleted_at IS NULL AND (
+            (COALESCE(lyrics, '') = '' AND $2 <> '')
+            OR (COALESCE(link, '') = '' AND $3 <> '')
+            OR ((release_date IS NULL OR release_date = '0001-01-01') AND $4::date IS NOT NULL)
+        )
+    `
+	var filled bool
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		edited, err := editedSongFields(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		fill := withoutEdited(song, edited)
+		result, err := tx.ExecContext(ctx, query, id, fill.Lyrics, fill.Link, fill.ReleaseDate)
+		if err != nil {
+			return fmt.Errorf("failed to fill missing fields: %w", withContextError(ctx, err))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		filled = affected > 0
+		if !filled {
+			return nil
+		}
+		return r.recordRevision(ctx, tx, id)
+	})
+	if err != nil {
+		return false, err
+	}
+	return filled, nil
+}
+
+func scanSongs(ctx context.Context, rows *sql.Rows) ([]models.Song, error) {
+	defer rows.Close()
+	var songs []models.Song
+	for rows.Next() {
+		var song models.Song
+		if err := rows.Scan(songFields(&song)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return songs, nil
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"song-library/internal/models"
+	"song-library/pkg/normalize"
+	"time"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revisionFields lists the song fields tracked by revisions, in the order
+// they are reported in ChangedFields.
+var revisionFields = []string{"group", "song", "release_date", "lyrics", "link"}
+
+// changedFields returns the tracked fields that differ between prev and cur.
+// A nil prev means the song was just created, so every field counts as changed.
+func changedFields(prev *models.Song, cur models.Song) []string {
+	if prev == nil {
+		return append([]string(nil), revisionFields...)
+	}
+
+	changed := []string{}
+	if prev.Group != cur.Group {
+		changed = append(changed, "group")
+	}
+	if prev.Song != cur.Song {
+		changed = append(changed, "song")
+	}
+	if !sameDate(prev.ReleaseDate, cur.ReleaseDate) {
+		changed = append(changed, "release_date")
+	}
+	if prev.Lyrics != cur.Lyrics {
+		changed = append(changed, "lyrics")
+	}
+	if prev.Link != cur.Link {
+		changed = append(changed, "link")
+	}
+	return changed
+}
+
+// editedFields returns the tracked fields some revision after the first one
+// changed: fields a person has since set or cleared.
+func editedFields(revisions []models.SongRevision) map[string]bool {
+	edited := make(map[string]bool)
+	for _, revision := range revisions {
+		if revision.Revision > 1 {
+			for _, field := range revision.ChangedFields {
+				edited[field] = true
+			}
+		}
+	}
+	return edited
+}
+
+// withoutEdited blanks the fields of a fill that were edited since the song
+// was created, so FillMissingFields leaves them alone.
+func withoutEdited(song models.Song, edited map[string]bool) models.Song {
+	if edited["lyrics"] {
+		song.Lyrics = ""
+	}
+	if edited["link"] {
+		song.Link = ""
+	}
+	if edited["release_date"] {
+		song.ReleaseDate = nil
+	}
+	return song
+}
+
+func sameDate(a, b *time.Time) bool {
+	if isZeroDate(a) || isZeroDate(b) {
+		return isZeroDate(a) == isZeroDate(b)
+	}
+	return truncateToDate(a).Equal(*truncateToDate(b))
+}
+
+// revisionColumns is the select list read by revisionFieldsOf.
+const revisionColumns = `sr.song_id, sr.revision, sr.group_name, sr.song, sr.release_date,
+        COALESCE(sr.lyrics, ''), COALESCE(sr.link, ''), sr.changed_fields, COALESCE(sr.created_at, TIMESTAMP 'epoch')`
+
+func revisionFieldsOf(revision *models.SongRevision) []any {
+	return []any{
+		&revision.SongID, &revision.Revision, &revision.Song.Group, &revision.Song.Song, &revision.Song.ReleaseDate,
+		&revision.Song.Lyrics, &revision.Song.Link, (*pq.StringArray)(&revision.ChangedFields), &revision.CreatedAt,
+	}
+}
+
+// recordRevision snapshots the current state of a song as its next revision.
+// Writes that changed none of the tracked fields are not recorded.
+func (r *SongRepository) recordRevision(ctx context.Context, q dbtx, songID int) error {
+	var current models.Song
+	query := `
+        SELECT ` + songColumns + `
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        WHERE s.id = $1
+    `
+	if err := q.QueryRowContext(ctx, query, songID).Scan(songFields(&current)...); err != nil {
+		return fmt.Errorf("failed to read song for revision: %w", withContextError(ctx, err))
+	}
+
+	var latest models.SongRevision
+	query = `
+        SELECT ` + revisionColumns + `
+        FROM song_revisions sr
+        WHERE sr.song_id = $1
+        ORDER BY sr.revision DESC
+        LIMIT 1
+    `
+	var prev *models.Song
+	err := q.QueryRowContext(ctx, query, songID).Scan(revisionFieldsOf(&latest)...)
+	switch {
+	case err == nil:
+		prev = &latest.Song
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return fmt.Errorf("failed to read latest revision: %w", withContextError(ctx, err))
+	}
+
+	changed := changedFields(prev, current)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	query = `
+        INSERT INTO song_revisions (song_id, revision, group_name, song, release_date, lyrics, link, changed_fields)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	_, err = q.ExecContext(ctx, query, songID, latest.Revision+1, current.Group, current.Song,
+		current.ReleaseDate, current.Lyrics, current.Link, pq.Array(changed))
+	if err != nil {
+		return fmt.Errorf("failed to insert revision: %w", withContextError(ctx, err))
+	}
+	return nil
+}
+
+// editedSongFields is editedFields read from the stored history of a song.
+func editedSongFields(ctx context.Context, q dbtx, songID int) (map[string]bool, error) {
+	query := `
+        SELECT DISTINCT unnest(changed_fields)
+        FROM song_revisions
+        WHERE song_id = $1 AND revision > 1
+    `
+	rows, err := q.QueryContext(ctx, query, songID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited fields: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	edited := make(map[string]bool)
+	for rows.Next() {
+		var field string
+		if err := rows.Scan(&field); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		edited[field] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return edited, nil
+}
+
+// GetSongRevisions returns the history of a live song, newest first.
+func (r *SongRepository) GetSongRevisions(ctx context.Context, songID int) ([]models.SongRevision, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_song_revisions")
+	defer cancel()
+
+	query := `
+        SELECT ` + revisionColumns + `
+        FROM song_revisions sr
+        JOIN songs s ON s.id = sr.song_id
+        WHERE sr.song_id = $1 AND s.deleted_at IS NULL
+        ORDER BY sr.revision DESC
+    `
+	rows, err := r.DB.QueryContext(ctx, query, songID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var revisions []models.SongRevision
+	for rows.Next() {
+		var revision models.SongRevision
+		if err := rows.Scan(revisionFieldsOf(&revision)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		revision.Song.ID = revision.SongID
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	if len(revisions) == 0 {
+		return nil, ErrSongNotFound
+	}
+	return revisions, nil
+}
+
+func (r *SongRepository) GetSongRevision(ctx context.Context, songID, revision int) (*models.SongRevision, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "get_song_revisions")
+	defer cancel()
+
+	return r.getSongRevision(ctx, r.DB, songID, revision)
+}
+
+func (r *SongRepository) getSongRevision(ctx context.Context, q dbtx, songID, revision int) (*models.SongRevision, error) {
+	exists, err := songExists(ctx, q, songID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrSongNotFound
+	}
+
+	query := `
+        SELECT ` + revisionColumns + `
+        FROM song_revisions sr
+        WHERE sr.song_id = $1 AND sr.revision = $2
+    `
+	var result models.SongRevision
+	if err := q.QueryRowContext(ctx, query, songID, revision).Scan(revisionFieldsOf(&result)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("error fetching revision: %w", withContextError(ctx, err))
+	}
+	result.Song.ID = songID
+	return &result, nil
+}
+
+// RollbackSong overwrites a song with the snapshot stored in one of its
+// revisions. The rollback is itself recorded as a new revision.
+func (r *SongRepository) RollbackSong(ctx context.Context, songID, revision int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "rollback_song")
+	defer cancel()
+
+	err := r.UnitOfWork.Run(ctx, func(tx *sql.Tx) error {
+		target, err := r.getSongRevision(ctx, tx, songID, revision)
+		if err != nil {
+			return err
+		}
+
+		groupID, err := r.getOrCreateGroupID(ctx, tx, target.Song.Group)
+		if err != nil {
+			return fmt.Errorf("failed to get or create group ID: %w", err)
+		}
+
+		if err := checkDuplicateSong(ctx, tx, groupID, target.Song.Song, songID); err != nil {
+			return err
+		}
+
+		query := `
+        UPDATE songs
+        SET group_id = $1, song = $2, title_key = $3, release_date = $4, lyrics = $5, link = $6,
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $7
+    `
+		_, err = tx.ExecContext(ctx, query, groupID, target.Song.Song, normalize.Key(target.Song.Song), target.Song.ReleaseDate, target.Song.Lyrics, target.Song.Link, songID)
+		if err != nil {
+			return fmt.Errorf("failed to roll back song: %w", withContextError(ctx, err))
+		}
+		return r.recordRevision(ctx, tx, songID)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully rolled back song with ID %d to revision %d", songID, revision)
+	return nil
+}
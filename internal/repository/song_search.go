@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidSearchQuery  = errors.New("search query has no searchable terms")
+	ErrUnsupportedLanguage = errors.New("unsupported search language")
+)
+
+// SearchLanguages lists the text search configurations that have a matching
+// index (see migrations/2_add_song_search.up.sql). "simple" is backed by the
+// generated search_vector column and is the default.
+var SearchLanguages = []string{"simple", "english", "russian"}
+
+// SongSearcher is implemented by stores that support full-text search.
+type SongSearcher interface {
+	SearchSongs(ctx context.Context, query SearchQuery) ([]models.SongSearchResult, error)
+}
+
+type SearchQuery struct {
+	// Text supports bare words (all must match), "quoted phrases" and
+	// prefix* terms.
+	Text     string
+	Language string
+	Page     int
+	Limit    int
+}
+
+func (r *SongRepository) SearchSongs(ctx context.Context, search SearchQuery) ([]models.SongSearchResult, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "search_songs")
+	defer cancel()
+
+	if search.Page < 1 || search.Limit < 1 {
+		return nil, fmt.Errorf("page and limit must be greater than 0")
+	}
+	language := search.Language
+	if language == "" {
+		language = SearchLanguages[0]
+	}
+	if !isSearchLanguage(language) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, language)
+	}
+	tsquery, err := buildTSQuery(search.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	// The language is interpolated rather than bound so the planner can match
+	// the expression indexes; it has been checked against SearchLanguages.
+	config := "'" + language + "'::regconfig"
+	document := "s.search_vector"
+	if language != "simple" {
+		document = `(setweight(to_tsvector(` + config + `, COALESCE(s.song, '')), 'A') ||
+            setweight(to_tsvector(` + config + `, replace(COALESCE(s.lyrics, ''), '\n', ' ')), 'B'))`
+	}
+	tsQueryExpr := "to_tsquery(" + config + ", $1)"
+	lyrics := `replace(COALESCE(s.lyrics, ''), '\n', E'\n')`
+
+	query := `
+        SELECT ` + songColumns + `,
+            ts_rank(` + document + `, ` + tsQueryExpr + `) AS rank,
+            COALESCE(verse.snippet, ts_headline(` + config + `, ` + lyrics + `, ` + tsQueryExpr + `,
+                'StartSel=<b>, StopSel=</b>, MaxFragments=1, MaxWords=30, MinWords=10'))
+        FROM songs s
+        JOIN groups g ON s.group_id = g.id
+        LEFT JOIN LATERAL (
+            SELECT ts_headline(` + config + `, v.text, ` + tsQueryExpr + `,
+                'StartSel=<b>, StopSel=</b>, HighlightAll=true') AS snippet
+            FROM unnest(string_to_array(` + lyrics + `, E'\n\n')) WITH ORDINALITY AS v(text, n)
+            WHERE to_tsvector(` + config + `, v.text) @@ ` + tsQueryExpr + `
+            ORDER BY v.n
+            LIMIT 1
+        ) verse ON true
+        WHERE s.deleted_at IS NULL AND ` + document + ` @@ ` + tsQueryExpr + `
+        ORDER BY rank DESC, s.id
+        LIMIT $2 OFFSET $3
+    `
+	rows, err := r.DB.QueryContext(ctx, query, tsquery, search.Limit, (search.Page-1)*search.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error executing search: %w", withContextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var results []models.SongSearchResult
+	for rows.Next() {
+		var result models.SongSearchResult
+		if err := rows.Scan(append(songFields(&result.Song), &result.Rank, &result.Snippet)...); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", withContextError(ctx, err))
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", withContextError(ctx, err))
+	}
+	return results, nil
+}
+
+func isSearchLanguage(language string) bool {
+	for _, supported := range SearchLanguages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// buildTSQuery translates user input into to_tsquery syntax. Quoted phrases
+// become <-> sequences, a trailing * marks a prefix term and everything else
+// is ANDed. Operator characters are stripped from terms so user input can
+// never produce a malformed tsquery.
+func buildTSQuery(text string) (string, error) {
+	var clauses []string
+	for i, part := range strings.Split(text, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.Join(searchTerms(part, false), " <-> "); phrase != "" {
+				clauses = append(clauses, "("+phrase+")")
+			}
+			continue
+		}
+		clauses = append(clauses, searchTerms(part, true)...)
+	}
+	if len(clauses) == 0 {
+		return "", ErrInvalidSearchQuery
+	}
+	return strings.Join(clauses, " & "), nil
+}
+
+func searchTerms(text string, allowPrefix bool) []string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		prefix := allowPrefix && strings.HasSuffix(word, "*")
+		term := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if term == "" {
+			continue
+		}
+		if prefix {
+			term += ":*"
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+// sortField describes a whitelisted sort key: the SQL expression it orders
+// by, the cast applied to keyset values and how to read the value from a song.
+// Nullable columns are coalesced so ordering and keyset comparisons agree.
+type sortField struct {
+	expr  string
+	cast  string
+	value func(song models.Song) string
+}
+
+const keysetTimeLayout = "2006-01-02T15:04:05.000000"
+
+var sortFields = map[string]sortField{
+	"id": {
+		expr:  "s.id",
+		cast:  "::int",
+		value: func(song models.Song) string { return strconv.Itoa(song.ID) },
+	},
+	"group": {
+		expr:  "g.name",
+		cast:  "::text",
+		value: func(song models.Song) string { return song.Group },
+	},
+	"song": {
+		expr:  "s.song",
+		cast:  "::text",
+		value: func(song models.Song) string { return song.Song },
+	},
+	"release_date": {
+		expr: "COALESCE(s.release_date, DATE '0001-01-01')",
+		cast: "::date",
+		value: func(song models.Song) string {
+			if song.ReleaseDate == nil {
+				return "0001-01-01"
+			}
+			return song.ReleaseDate.Format("2006-01-02")
+		},
+	},
+	"created_at": {
+		expr:  "COALESCE(s.created_at, TIMESTAMP 'epoch')",
+		cast:  "::timestamp",
+		value: func(song models.Song) string { return song.CreatedAt.UTC().Format(keysetTimeLayout) },
+	},
+	"updated_at": {
+		expr:  "COALESCE(s.updated_at, TIMESTAMP 'epoch')",
+		cast:  "::timestamp",
+		value: func(song models.Song) string { return song.UpdatedAt.UTC().Format(keysetTimeLayout) },
+	},
+}
+
+// SortableFields lists the keys accepted by ParseSongSort.
+var SortableFields = []string{"id", "group", "song", "release_date", "created_at", "updated_at"}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// SongSort is an ordered list of sort keys that always ends with id, so the
+// order is total and keyset pagination is deterministic.
+type SongSort []SortKey
+
+// ParseSongSort parses a comma separated list such as "release_date,-song".
+// A leading - sorts descending, a leading + (or none) ascending.
+func ParseSongSort(spec string) (SongSort, error) {
+	var sort SongSort
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{Field: strings.TrimLeft(part, "+-"), Desc: strings.HasPrefix(part, "-")}
+		if _, ok := sortFields[key.Field]; !ok || len(part)-len(key.Field) > 1 {
+			return nil, fmt.Errorf("%w: unknown sort key %q", ErrInvalidSort, part)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("%w: duplicate sort key %q", ErrInvalidSort, key.Field)
+		}
+		seen[key.Field] = true
+		sort = append(sort, key)
+	}
+	return sort.normalize(), nil
+}
+
+// normalize appends the id tie-breaker unless id is already a sort key.
+func (s SongSort) normalize() SongSort {
+	for _, key := range s {
+		if key.Field == "id" {
+			return s
+		}
+	}
+	return append(s[:len(s):len(s)], SortKey{Field: "id"})
+}
+
+func (s SongSort) String() string {
+	parts := make([]string, 0, len(s))
+	for _, key := range s.normalize() {
+		if key.Desc {
+			parts = append(parts, "-"+key.Field)
+		} else {
+			parts = append(parts, key.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// orderBy renders the ORDER BY list, with every direction flipped when
+// walking backwards.
+func (s SongSort) orderBy(backward bool) string {
+	parts := make([]string, 0, len(s))
+	for _, key := range s.normalize() {
+		direction := "ASC"
+		if key.Desc != backward {
+			direction = "DESC"
+		}
+		parts = append(parts, sortFields[key.Field].expr+" "+direction)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Values returns the keyset position of song under this sort.
+func (s SongSort) Values(song models.Song) []string {
+	keys := s.normalize()
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = sortFields[key.Field].value(song)
+	}
+	return values
+}
+
+// keysetCondition renders the row-value comparison selecting rows that come
+// after (or, when walking backwards, before) the keyset position. Keys with
+// mixed directions are expanded into (a > x) OR (a = x AND b < y) ...
+func (s SongSort) keysetCondition(b *queryBuilder, keyset Keyset) (string, error) {
+	keys := s.normalize()
+	if len(keyset.Values) != len(keys) {
+		return "", fmt.Errorf("keyset has %d values, sort has %d keys", len(keyset.Values), len(keys))
+	}
+
+	placeholders := make([]string, len(keys))
+	for i, key := range keys {
+		placeholders[i] = b.arg(keyset.Values[i]) + sortFields[key.Field].cast
+	}
+
+	var terms []string
+	var equal []string
+	for i, key := range keys {
+		comparison := ">"
+		if key.Desc != keyset.Backward {
+			comparison = "<"
+		}
+		expr := sortFields[key.Field].expr
+		term := append(equal[:len(equal):len(equal)], expr+" "+comparison+" "+placeholders[i])
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+		equal = append(equal, expr+" = "+placeholders[i])
+	}
+	if keyset.Inclusive {
+		terms = append(terms, "("+strings.Join(equal, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", nil
+}
+
+// compare orders two songs the same way orderBy does. Text keys use byte-wise
+// comparison, which can differ from the database collation for non-ASCII text.
+func (s SongSort) compare(a, b models.Song) int {
+	return s.compareValues(s.Values(a), s.Values(b))
+}
+
+func (s SongSort) compareValues(a, b []string) int {
+	for i, key := range s.normalize() {
+		c := compareSortValue(key.Field, a[i], b[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareSortValue(field, a, b string) int {
+	if field == "id" {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	}
+	return strings.Compare(a, b)
+}
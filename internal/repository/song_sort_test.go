@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSongSort(t *testing.T) {
+	cases := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "", want: "id"},
+		{spec: "id", want: "id"},
+		{spec: "-id", want: "-id"},
+		{spec: "song", want: "song,id"},
+		{spec: "+song", want: "song,id"},
+		{spec: "release_date,-song,group", want: "release_date,-song,group,id"},
+		{spec: "-updated_at,id", want: "-updated_at,id"},
+		{spec: " created_at , -group ,", want: "created_at,-group,id"},
+		{spec: "genre", wantErr: true},
+		{spec: "SONG", wantErr: true},
+		{spec: "--song", wantErr: true},
+		{spec: "+-song", wantErr: true},
+		{spec: "song,-song", wantErr: true},
+		{spec: "id,id", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			sort, err := ParseSongSort(tc.spec)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidSort) {
+					t.Errorf("got %v, %v, want ErrInvalidSort", sort, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sort.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSortableFieldsMatchWhitelist(t *testing.T) {
+	if len(SortableFields) != len(sortFields) {
+		t.Fatalf("SortableFields has %d keys, the whitelist %d", len(SortableFields), len(sortFields))
+	}
+	for _, field := range SortableFields {
+		if _, err := ParseSongSort(field); err != nil {
+			t.Errorf("ParseSongSort(%q): %v", field, err)
+		}
+	}
+}
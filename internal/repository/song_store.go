@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"time"
+)
+
+var (
+	ErrSongNotFound    = errors.New("song not found")
+	ErrVersionMismatch = errors.New("song version mismatch")
+	ErrDuplicateSong   = errors.New("song already exists")
+)
+
+// DuplicateSongError is returned by writes that would give a group a second
+// live song with the same title. ExistingID is the song that already has it.
+type DuplicateSongError struct {
+	ExistingID int
+}
+
+func (e *DuplicateSongError) Error() string {
+	return fmt.Sprintf("%v: song %d has the same group and title", ErrDuplicateSong, e.ExistingID)
+}
+
+func (e *DuplicateSongError) Unwrap() error {
+	return ErrDuplicateSong
+}
+
+// SongStore is the persistence contract for songs. SongRepository implements
+// it on top of PostgreSQL and MemorySongRepository keeps everything in memory.
+//
+// Deletes are soft: DeleteSong moves a song to the trash, where it is hidden
+// from every read except a Trashed SongFilter until RestoreSong or PurgeSong.
+// Every write that changes a song's content is recorded as a revision.
+//
+// A group holds at most one live song per title, compared by name key. Writes
+// that would break this fail with a *DuplicateSongError, and MergeGroups with
+// ErrDuplicateSong; UpsertSong replaces the live song instead.
+//
+// Each write also bumps the song's Version. A non-zero ifVersion makes
+// UpdateSong, PatchSong and DeleteSong fail with ErrVersionMismatch unless it
+// equals the stored version.
+type SongStore interface {
+	GetSongs(ctx context.Context, filter SongFilter, sort SongSort, page, limit int) ([]models.Song, error)
+	CountSongs(ctx context.Context, filter SongFilter) (int, error)
+	GetSongsByKeyset(ctx context.Context, filter SongFilter, sort SongSort, keyset Keyset, limit int) (*KeysetPage, error)
+	StreamSongs(ctx context.Context, filter SongFilter, sort SongSort, omitLyrics bool, fn func(song models.Song) error) error
+	GetSongByID(ctx context.Context, songID string) (*models.Song, error)
+	AddSong(ctx context.Context, song models.Song) (*models.Song, error)
+	UpsertSong(ctx context.Context, song models.Song) (*models.Song, bool, error)
+	AddSongs(ctx context.Context, songs []models.Song, opts BulkOptions) ([]BulkOutcome, error)
+	FindDuplicateSongs(ctx context.Context, songs []models.Song) ([]int, error)
+	UpdateSong(ctx context.Context, id int, song models.Song, ifVersion int) error
+	PatchSong(ctx context.Context, id int, changes SongChanges, ifVersion int) error
+	DeleteSong(ctx context.Context, id int, ifVersion int) error
+	RestoreSong(ctx context.Context, id int) error
+	PurgeSong(ctx context.Context, id int) error
+	PurgeDeletedSongs(ctx context.Context, cutoff time.Time) (int, error)
+	GetIncompleteSongs(ctx context.Context, afterID, limit int) ([]models.Song, error)
+	FillMissingFields(ctx context.Context, id int, song models.Song) (bool, error)
+	GetSongRevisions(ctx context.Context, songID int) ([]models.SongRevision, error)
+	GetSongRevision(ctx context.Context, songID, revision int) (*models.SongRevision, error)
+	RollbackSong(ctx context.Context, songID, revision int) error
+}
+
+var (
+	_ SongStore = (*SongRepository)(nil)
+	_ SongStore = (*MemorySongRepository)(nil)
+)
@@ -0,0 +1,442 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/lib/pq"
+	"os"
+	"song-library/internal/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testDSNEnv names the environment variable holding the DSN of a scratch
+// PostgreSQL database. When it is set the contract also runs against
+// SongRepository; every case starts from empty tables.
+const testDSNEnv = "SONG_LIBRARY_TEST_DSN"
+
+// contractStore is what both song stores implement.
+type contractStore interface {
+	SongStore
+	GroupStore
+}
+
+// contractStores returns a constructor of a fresh, empty store per
+// implementation under test.
+func contractStores(t *testing.T) map[string]func(t *testing.T) contractStore {
+	stores := map[string]func(t *testing.T) contractStore{
+		"memory": func(t *testing.T) contractStore { return NewMemorySongRepository() },
+	}
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Logf("%s is not set, skipping SongRepository", testDSNEnv)
+		return stores
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrateTestDB(t, db)
+
+	stores["postgres"] = func(t *testing.T) contractStore {
+		query := `TRUNCATE album_tracks, albums, song_revisions, songs, group_aliases, groups RESTART IDENTITY CASCADE`
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+		return NewSongRepository(db, QueryTimeouts{Default: 10 * time.Second})
+	}
+	return stores
+}
+
+func migrateTestDB(t *testing.T, db *sql.DB) {
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("create migrations driver: %v", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../migrations", "song", driver)
+	if err != nil {
+		t.Fatalf("create migrate instance: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("apply migrations: %v", err)
+	}
+}
+
+func contractSong(group, title string) models.Song {
+	release := time.Date(2017, time.February, 1, 0, 0, 0, 0, time.UTC)
+	return models.Song{Group: group, Song: title, ReleaseDate: &release, Lyrics: "la la", Link: "https://example.com/" + title}
+}
+
+func songTitles(songs []models.Song) []string {
+	titles := make([]string, len(songs))
+	for i, song := range songs {
+		titles[i] = song.Song
+	}
+	return titles
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSongStoreContract(t *testing.T) {
+	byID, err := ParseSongSort("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := []models.Song{
+		contractSong("Imagine Dragons", "Believer"),
+		contractSong("Imagine Dragons", "Thunder"),
+		contractSong("Arctic Monkeys", "Do I Wanna Know?"),
+		contractSong("Arctic Monkeys", "R U Mine?"),
+		contractSong("The Killers", "Mr. Brightside"),
+	}
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, store contractStore)
+	}{
+		{
+			name: "group filter is a case-insensitive substring match",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				songs, err := store.GetSongs(ctx, SongFilter{Group: "monKEYS"}, byID, 1, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := []string{"Do I Wanna Know?", "R U Mine?"}; !sameStrings(songTitles(songs), want) {
+					t.Errorf("got %v, want %v", songTitles(songs), want)
+				}
+			},
+		},
+		{
+			name: "song filter is a case-insensitive substring match",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				filter := SongFilter{Song: "BRIGHT"}
+				songs, err := store.GetSongs(ctx, filter, byID, 1, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := []string{"Mr. Brightside"}; !sameStrings(songTitles(songs), want) {
+					t.Errorf("got %v, want %v", songTitles(songs), want)
+				}
+				total, err := store.CountSongs(ctx, filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != 1 {
+					t.Errorf("CountSongs = %d, want 1", total)
+				}
+			},
+		},
+		{
+			name: "group and song filters combine",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				songs, err := store.GetSongs(ctx, SongFilter{Group: "dragons", Song: "er"}, byID, 1, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := []string{"Believer", "Thunder"}; !sameStrings(songTitles(songs), want) {
+					t.Errorf("got %v, want %v", songTitles(songs), want)
+				}
+			},
+		},
+		{
+			name: "link host matches subdomains literally",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if _, err := store.AddSong(ctx, models.Song{Group: "Muse", Song: "Hysteria", Link: "https://music.example.com/hysteria"}); err != nil {
+					t.Fatal(err)
+				}
+				for host, want := range map[string]int{"example.com": 6, "music.example.com": 1, "%ample.com": 0, "_xample.com": 0} {
+					total, err := store.CountSongs(ctx, SongFilter{LinkHost: host})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if total != want {
+						t.Errorf("link host %q: CountSongs = %d, want %d", host, total, want)
+					}
+				}
+			},
+		},
+		{
+			name: "pagination splits the ordered result",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				pages := [][]string{
+					{"Believer", "Thunder"},
+					{"Do I Wanna Know?", "R U Mine?"},
+					{"Mr. Brightside"},
+					{},
+				}
+				for i, want := range pages {
+					songs, err := store.GetSongs(ctx, SongFilter{}, byID, i+1, 2)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !sameStrings(songTitles(songs), want) {
+						t.Errorf("page %d: got %v, want %v", i+1, songTitles(songs), want)
+					}
+				}
+				total, err := store.CountSongs(ctx, SongFilter{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != len(seed) {
+					t.Errorf("CountSongs = %d, want %d", total, len(seed))
+				}
+			},
+		},
+		{
+			name: "pagination rejects non-positive page and limit",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if _, err := store.GetSongs(ctx, SongFilter{}, byID, 0, 2); err == nil {
+					t.Error("page 0: expected an error")
+				}
+				if _, err := store.GetSongs(ctx, SongFilter{}, byID, 1, 0); err == nil {
+					t.Error("limit 0: expected an error")
+				}
+			},
+		},
+		{
+			name: "adding a song creates its group once",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if _, err := store.AddSong(ctx, contractSong("Muse", "Hysteria")); err != nil {
+					t.Fatal(err)
+				}
+				added, err := store.AddSong(ctx, contractSong("muse", "Uprising"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if added.Group != "Muse" {
+					t.Errorf("second song filed under %q, want the existing group %q", added.Group, "Muse")
+				}
+
+				groups, err := store.GetGroups(ctx, "muse", 1, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(groups) != 1 || groups[0].Name != "Muse" || groups[0].SongCount != 2 {
+					t.Errorf("got groups %+v, want one Muse group with 2 songs", groups)
+				}
+				total, err := store.CountGroups(ctx, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != 4 {
+					t.Errorf("CountGroups = %d, want 4", total)
+				}
+			},
+		},
+		{
+			name: "added songs are returned as stored",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				added, err := store.AddSong(ctx, contractSong("Muse", "Hysteria"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if added.ID == 0 || added.Version != 1 || added.Song != "Hysteria" {
+					t.Errorf("got %+v, want a new song at version 1", added)
+				}
+				fetched, err := store.GetSongByID(ctx, strconv.Itoa(added.ID))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fetched.Song != added.Song || fetched.Group != added.Group || fetched.Link != added.Link {
+					t.Errorf("GetSongByID = %+v, want %+v", fetched, added)
+				}
+			},
+		},
+		{
+			name: "duplicate titles are refused regardless of case",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				_, err := store.AddSong(ctx, contractSong("imagine dragons", "BELIEVER"))
+				var duplicate *DuplicateSongError
+				if !errors.As(err, &duplicate) || duplicate.ExistingID != 1 {
+					t.Errorf("got %v, want a duplicate of song 1", err)
+				}
+			},
+		},
+		{
+			name: "upserts replace the live song with the same title key",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				update := contractSong("imagine dragons", "BELIEVER")
+				update.Lyrics = "first things first"
+				stored, created, err := store.UpsertSong(ctx, update)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if created || stored.ID != 1 || stored.Version != 2 || stored.Lyrics != update.Lyrics {
+					t.Errorf("got %+v (created %v), want song 1 replaced at version 2", stored, created)
+				}
+				if stored.Group != "Imagine Dragons" {
+					t.Errorf("replaced song filed under %q, want %q", stored.Group, "Imagine Dragons")
+				}
+
+				stored, created, err = store.UpsertSong(ctx, contractSong("Muse", "Hysteria"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !created || stored.Version != 1 {
+					t.Errorf("got %+v (created %v), want a new song", stored, created)
+				}
+			},
+		},
+		{
+			name: "filling missing fields skips fields edited since creation",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				added, err := store.AddSong(ctx, models.Song{Group: "Muse", Song: "Hysteria"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				lyrics, cleared := "It's bugging me", ""
+				if err := store.PatchSong(ctx, added.ID, SongChanges{Lyrics: &lyrics}, 0); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.PatchSong(ctx, added.ID, SongChanges{Lyrics: &cleared}, 0); err != nil {
+					t.Fatal(err)
+				}
+
+				release := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+				fill := models.Song{Lyrics: "enriched", Link: "https://example.com/hysteria", ReleaseDate: &release}
+				filled, err := store.FillMissingFields(ctx, added.ID, fill)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !filled {
+					t.Fatal("expected the link and release date to be filled")
+				}
+				stored, err := store.GetSongByID(ctx, strconv.Itoa(added.ID))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stored.Lyrics != "" || stored.Link != fill.Link || stored.ReleaseDate == nil || !stored.ReleaseDate.Equal(release) {
+					t.Errorf("got %+v, want the cleared lyrics kept and the rest filled", stored)
+				}
+
+				filled, err = store.FillMissingFields(ctx, added.ID, fill)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if filled {
+					t.Error("second fill changed the song, want nothing left to fill")
+				}
+			},
+		},
+		{
+			name: "deleting a missing or trashed song fails",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if err := store.DeleteSong(ctx, 1, 0); err != nil {
+					t.Fatal(err)
+				}
+				for _, id := range []int{1, 99} {
+					if err := store.DeleteSong(ctx, id, 0); !errors.Is(err, ErrSongNotFound) {
+						t.Errorf("delete song %d: got %v, want ErrSongNotFound", id, err)
+					}
+				}
+			},
+		},
+		{
+			name: "updating a missing or trashed song fails without creating its group",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if err := store.DeleteSong(ctx, 1, 0); err != nil {
+					t.Fatal(err)
+				}
+				for _, id := range []int{1, 99} {
+					if err := store.UpdateSong(ctx, id, contractSong("Muse", "Hysteria"), 0); !errors.Is(err, ErrSongNotFound) {
+						t.Errorf("update song %d: got %v, want ErrSongNotFound", id, err)
+					}
+				}
+				total, err := store.CountGroups(ctx, "muse")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != 0 {
+					t.Errorf("CountGroups(muse) = %d, want no group left behind", total)
+				}
+			},
+		},
+		{
+			name: "bulk inserts dedupe titles by key",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				outcomes, err := store.AddSongs(ctx, []models.Song{
+					contractSong("Muse", "Hysteria"),
+					contractSong("muse", "HYSTERIA"),
+					contractSong("Imagine Dragons", "believer"),
+				}, BulkOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if outcomes[0].ID == 0 || outcomes[1].ID != 0 || outcomes[1].DuplicateOf != outcomes[0].ID {
+					t.Errorf("got %+v, want the second song to repeat the first", outcomes)
+				}
+				if outcomes[2].ID != 0 || outcomes[2].DuplicateOf != 1 {
+					t.Errorf("got %+v, want the third song to duplicate song 1", outcomes[2])
+				}
+				total, err := store.CountSongs(ctx, SongFilter{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if total != len(seed)+1 {
+					t.Errorf("CountSongs = %d, want %d", total, len(seed)+1)
+				}
+			},
+		},
+		{
+			name: "duplicate lookups match live songs by key",
+			run: func(t *testing.T, ctx context.Context, store contractStore) {
+				if err := store.DeleteSong(ctx, 2, 0); err != nil {
+					t.Fatal(err)
+				}
+				ids, err := store.FindDuplicateSongs(ctx, []models.Song{
+					contractSong("imagine  dragons", "BELIEVER"),
+					contractSong("Imagine Dragons", "Thunder"),
+					contractSong("Muse", "Hysteria"),
+					contractSong("The Killers", "Mr. Brightside"),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := []int{1, 0, 0, 5}
+				if len(ids) != len(want) {
+					t.Fatalf("got %v, want %v", ids, want)
+				}
+				for i := range want {
+					if ids[i] != want[i] {
+						t.Errorf("got %v, want %v", ids, want)
+						break
+					}
+				}
+			},
+		},
+	}
+
+	for name, newStore := range contractStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					ctx := context.Background()
+					store := newStore(t)
+					for _, song := range seed {
+						if _, err := store.AddSong(ctx, song); err != nil {
+							t.Fatalf("seed %q: %v", song.Song, err)
+						}
+					}
+					tc.run(t, ctx, store)
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// QueryTimeouts bounds how long a single repository operation may run.
+// PerOperation is keyed by operation name (e.g. "get_songs") and falls back
+// to Default; a zero duration means no timeout beyond the caller's context.
+type QueryTimeouts struct {
+	Default      time.Duration
+	PerOperation map[string]time.Duration
+}
+
+func (t QueryTimeouts) For(operation string) time.Duration {
+	if timeout, ok := t.PerOperation[operation]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+func (t QueryTimeouts) apply(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout := t.For(operation)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withContextError attaches the context error to err when the query was
+// cancelled or timed out, since the driver may report it as a generic
+// "canceling statement" error that errors.Is cannot match.
+func withContextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx so helpers can run inside or
+// outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const defaultTxRetries = 3
+
+// UnitOfWork runs multi-statement operations in a single serializable
+// transaction, retrying the whole function when PostgreSQL aborts it with a
+// serialization failure or deadlock.
+type UnitOfWork struct {
+	DB         *sql.DB
+	MaxRetries int
+}
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{DB: db, MaxRetries: defaultTxRetries}
+}
+
+// Run calls fn inside a transaction and commits if it returns nil. fn may be
+// invoked more than once, so it must not have side effects outside tx.
+func (u *UnitOfWork) Run(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := u.runOnce(ctx, fn)
+		if err == nil || !isRetryableTxError(err) || attempt >= u.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt+1) * 10 * time.Millisecond):
+		case <-ctx.Done():
+			return withContextError(ctx, err)
+		}
+	}
+}
+
+func (u *UnitOfWork) runOnce(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := u.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", withContextError(ctx, err))
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", withContextError(ctx, err))
+	}
+	return nil
+}
+
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
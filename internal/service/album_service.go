@@ -0,0 +1,242 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"song-library/pkg/normalize"
+	"time"
+	"unicode/utf8"
+)
+
+// maxAlbumTitleLength matches the albums.title column.
+const maxAlbumTitleLength = 255
+
+var ErrInvalidAlbum = errors.New("invalid album")
+
+// AlbumTypes lists the accepted values of an album's type.
+var AlbumTypes = []models.AlbumType{models.AlbumLP, models.AlbumEP, models.AlbumSingle, models.AlbumCompilation}
+
+// AlbumTrackRequest places a song on an album. Disc defaults to 1.
+type AlbumTrackRequest struct {
+	SongID int `json:"song_id"`
+	Disc   int `json:"disc"`
+	Track  int `json:"track"`
+}
+
+// AlbumRequest is the body of POST /albums. Type defaults to lp.
+type AlbumRequest struct {
+	Title       string              `json:"title"`
+	Group       string              `json:"group"`
+	ReleaseDate *time.Time          `json:"release_date"`
+	Type        models.AlbumType    `json:"type"`
+	Tracks      []AlbumTrackRequest `json:"tracks"`
+}
+
+type AlbumPage struct {
+	Items      []models.Album `json:"items"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	Total      int            `json:"total"`
+	TotalPages int            `json:"total_pages"`
+}
+
+type AlbumService struct {
+	AlbumRepo repository.AlbumStore
+}
+
+func NewAlbumService(albumRepo repository.AlbumStore) *AlbumService {
+	return &AlbumService{AlbumRepo: albumRepo}
+}
+
+func (s *AlbumService) GetAlbums(ctx context.Context, filter repository.AlbumFilter, page, limit int) (*AlbumPage, error) {
+	albums, err := s.AlbumRepo.GetAlbums(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.AlbumRepo.CountAlbums(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if albums == nil {
+		albums = []models.Album{}
+	}
+	return &AlbumPage{
+		Items:      albums,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}, nil
+}
+
+func (s *AlbumService) GetAlbumByID(ctx context.Context, id int) (*models.Album, error) {
+	return s.AlbumRepo.GetAlbumByID(ctx, id)
+}
+
+func (s *AlbumService) GetAlbumTracks(ctx context.Context, id int) ([]models.AlbumTrack, error) {
+	tracks, err := s.AlbumRepo.GetAlbumTracks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tracks == nil {
+		tracks = []models.AlbumTrack{}
+	}
+	return tracks, nil
+}
+
+func (s *AlbumService) AddAlbum(ctx context.Context, request AlbumRequest) (*models.Album, error) {
+	album := models.Album{ReleaseDate: request.ReleaseDate, Type: request.Type}
+	var err error
+	if album.Title, err = albumName("title", request.Title); err != nil {
+		return nil, err
+	}
+	if album.Group, err = albumName("group", request.Group); err != nil {
+		return nil, err
+	}
+	if album.Type == "" {
+		album.Type = models.AlbumLP
+	}
+	if !validAlbumType(album.Type) {
+		return nil, fmt.Errorf("%w: type must be one of %v", ErrInvalidAlbum, AlbumTypes)
+	}
+	if album.ReleaseDate != nil && album.ReleaseDate.IsZero() {
+		album.ReleaseDate = nil
+	}
+	tracks, err := trackPositions(request.Tracks)
+	if err != nil {
+		return nil, err
+	}
+	return s.AlbumRepo.AddAlbum(ctx, album, tracks)
+}
+
+// PatchAlbum applies a merge patch to an album: members that are present
+// replace the current value, a null release_date clears it and tracks, when
+// present, replace the whole track list.
+func (s *AlbumService) PatchAlbum(ctx context.Context, id int, patch []byte) (*models.Album, error) {
+	changes, err := albumChanges(patch)
+	if err != nil {
+		return nil, err
+	}
+	if !changes.IsEmpty() {
+		if err := s.AlbumRepo.UpdateAlbum(ctx, id, changes); err != nil {
+			return nil, err
+		}
+	}
+	return s.AlbumRepo.GetAlbumByID(ctx, id)
+}
+
+func (s *AlbumService) DeleteAlbum(ctx context.Context, id int) error {
+	return s.AlbumRepo.DeleteAlbum(ctx, id)
+}
+
+// albumChanges turns a merge patch into the changes it makes.
+func albumChanges(patch []byte) (repository.AlbumChanges, error) {
+	var changes repository.AlbumChanges
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return changes, fmt.Errorf("%w: patch must be a JSON object", ErrInvalidAlbum)
+	}
+
+	for member, value := range members {
+		null := bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+		switch member {
+		case "title", "group":
+			var name string
+			if err := json.Unmarshal(value, &name); err != nil || null {
+				return changes, fmt.Errorf("%w: %s must be a string", ErrInvalidAlbum, member)
+			}
+			name, err := albumName(member, name)
+			if err != nil {
+				return changes, err
+			}
+			if member == "title" {
+				changes.Title = &name
+			} else {
+				changes.Group = &name
+			}
+		case "release_date":
+			var date time.Time
+			if !null {
+				if err := json.Unmarshal(value, &date); err != nil {
+					return changes, fmt.Errorf("%w: release_date must be an RFC 3339 date", ErrInvalidAlbum)
+				}
+			}
+			changes.ReleaseDate = &date
+		case "type":
+			var albumType models.AlbumType
+			if err := json.Unmarshal(value, &albumType); err != nil || !validAlbumType(albumType) {
+				return changes, fmt.Errorf("%w: type must be one of %v", ErrInvalidAlbum, AlbumTypes)
+			}
+			changes.Type = &albumType
+		case "tracks":
+			var requests []AlbumTrackRequest
+			if err := json.Unmarshal(value, &requests); err != nil {
+				return changes, fmt.Errorf("%w: tracks must be an array of tracks", ErrInvalidAlbum)
+			}
+			tracks, err := trackPositions(requests)
+			if err != nil {
+				return changes, err
+			}
+			// tracks is never nil, so null and [] both empty the album.
+			changes.Tracks = tracks
+		default:
+			return changes, fmt.Errorf("%w: unknown field %q", ErrInvalidAlbum, member)
+		}
+	}
+	return changes, nil
+}
+
+// albumName normalizes the title or group of an album like song names and
+// checks it fits its column.
+func albumName(field, value string) (string, error) {
+	name := normalize.Name(value)
+	if name == "" {
+		return "", fmt.Errorf("%w: %s cannot be empty", ErrInvalidAlbum, field)
+	}
+	if utf8.RuneCountInString(name) > maxAlbumTitleLength {
+		return "", fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidAlbum, field, maxAlbumTitleLength)
+	}
+	return name, nil
+}
+
+func validAlbumType(albumType models.AlbumType) bool {
+	for _, t := range AlbumTypes {
+		if albumType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// trackPositions validates a track list: every song appears once and every
+// disc and track number holds one song.
+func trackPositions(requests []AlbumTrackRequest) ([]repository.TrackPosition, error) {
+	tracks := make([]repository.TrackPosition, len(requests))
+	songs := make(map[int]bool, len(requests))
+	positions := make(map[[2]int]bool, len(requests))
+	for i, request := range requests {
+		track := repository.TrackPosition{SongID: request.SongID, Disc: request.Disc, Track: request.Track}
+		if track.Disc == 0 {
+			track.Disc = 1
+		}
+		switch {
+		case track.SongID < 1:
+			return nil, fmt.Errorf("%w: track %d has no song_id", ErrInvalidAlbum, i)
+		case track.Disc < 1 || track.Track < 1:
+			return nil, fmt.Errorf("%w: track %d must have positive disc and track numbers", ErrInvalidAlbum, i)
+		case songs[track.SongID]:
+			return nil, fmt.Errorf("%w: song %d is on the album more than once", ErrInvalidAlbum, track.SongID)
+		case positions[[2]int{track.Disc, track.Track}]:
+			return nil, fmt.Errorf("%w: disc %d track %d is taken more than once", ErrInvalidAlbum, track.Disc, track.Track)
+		}
+		songs[track.SongID] = true
+		positions[[2]int{track.Disc, track.Track}] = true
+		tracks[i] = track
+	}
+	return tracks, nil
+}
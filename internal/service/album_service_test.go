@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"testing"
+	"time"
+)
+
+func TestTrackPositions(t *testing.T) {
+	cases := []struct {
+		name     string
+		requests []AlbumTrackRequest
+		want     []repository.TrackPosition
+		wantErr  error
+	}{
+		{name: "no tracks", requests: nil, want: []repository.TrackPosition{}},
+		{
+			name:     "disc defaults to 1",
+			requests: []AlbumTrackRequest{{SongID: 3, Track: 1}, {SongID: 4, Disc: 2, Track: 1}},
+			want:     []repository.TrackPosition{{SongID: 3, Disc: 1, Track: 1}, {SongID: 4, Disc: 2, Track: 1}},
+		},
+		{name: "missing song", requests: []AlbumTrackRequest{{Track: 1}}, wantErr: ErrInvalidAlbum},
+		{name: "missing track number", requests: []AlbumTrackRequest{{SongID: 3}}, wantErr: ErrInvalidAlbum},
+		{name: "negative disc", requests: []AlbumTrackRequest{{SongID: 3, Disc: -1, Track: 1}}, wantErr: ErrInvalidAlbum},
+		{name: "duplicate song", requests: []AlbumTrackRequest{{SongID: 3, Track: 1}, {SongID: 3, Track: 2}}, wantErr: ErrInvalidAlbum},
+		{name: "duplicate position", requests: []AlbumTrackRequest{{SongID: 3, Track: 1}, {SongID: 4, Disc: 1, Track: 1}}, wantErr: ErrInvalidAlbum},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := trackPositions(tc.requests)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameTracks(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAlbumChanges(t *testing.T) {
+	title, group := "Absolution", "Muse"
+	ep := models.AlbumEP
+	release := time.Date(2003, time.September, 15, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		patch   string
+		want    repository.AlbumChanges
+		wantErr error
+	}{
+		{name: "empty patch", patch: `{}`},
+		{
+			name:  "names are normalized",
+			patch: `{"title": "  Absolution ", "group": "Muse", "type": "ep"}`,
+			want:  repository.AlbumChanges{Title: &title, Group: &group, Type: &ep},
+		},
+		{name: "release date", patch: `{"release_date": "2003-09-15T00:00:00Z"}`, want: repository.AlbumChanges{ReleaseDate: &release}},
+		{name: "null release date clears it", patch: `{"release_date": null}`, want: repository.AlbumChanges{ReleaseDate: &time.Time{}}},
+		{
+			name:  "tracks replace the list",
+			patch: `{"tracks": [{"song_id": 3, "track": 1}]}`,
+			want:  repository.AlbumChanges{Tracks: []repository.TrackPosition{{SongID: 3, Disc: 1, Track: 1}}},
+		},
+		{name: "null tracks clear the list", patch: `{"tracks": null}`, want: repository.AlbumChanges{Tracks: []repository.TrackPosition{}}},
+		{name: "empty tracks clear the list", patch: `{"tracks": []}`, want: repository.AlbumChanges{Tracks: []repository.TrackPosition{}}},
+		{name: "duplicate track song", patch: `{"tracks": [{"song_id": 3, "track": 1}, {"song_id": 3, "track": 2}]}`, wantErr: ErrInvalidAlbum},
+		{name: "duplicate track position", patch: `{"tracks": [{"song_id": 3, "track": 1}, {"song_id": 4, "disc": 1, "track": 1}]}`, wantErr: ErrInvalidAlbum},
+		{name: "unknown member", patch: `{"genre": "rock"}`, wantErr: ErrInvalidAlbum},
+		{name: "null title", patch: `{"title": null}`, wantErr: ErrInvalidAlbum},
+		{name: "blank group", patch: `{"group": "  "}`, wantErr: ErrInvalidAlbum},
+		{name: "unknown type", patch: `{"type": "bootleg"}`, wantErr: ErrInvalidAlbum},
+		{name: "malformed release date", patch: `{"release_date": "15.09.2003"}`, wantErr: ErrInvalidAlbum},
+		{name: "not an object", patch: `[]`, wantErr: ErrInvalidAlbum},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := albumChanges([]byte(tc.patch))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameString(got.Title, tc.want.Title) || !sameString(got.Group, tc.want.Group) {
+				t.Errorf("title and group = %v, %v, want %v, %v", got.Title, got.Group, tc.want.Title, tc.want.Group)
+			}
+			if (got.Type == nil) != (tc.want.Type == nil) || got.Type != nil && *got.Type != *tc.want.Type {
+				t.Errorf("type = %v, want %v", got.Type, tc.want.Type)
+			}
+			if (got.ReleaseDate == nil) != (tc.want.ReleaseDate == nil) || got.ReleaseDate != nil && !got.ReleaseDate.Equal(*tc.want.ReleaseDate) {
+				t.Errorf("release date = %v, want %v", got.ReleaseDate, tc.want.ReleaseDate)
+			}
+			if (got.Tracks == nil) != (tc.want.Tracks == nil) || !sameTracks(got.Tracks, tc.want.Tracks) {
+				t.Errorf("tracks = %#v, want %#v", got.Tracks, tc.want.Tracks)
+			}
+		})
+	}
+}
+
+func sameString(a, b *string) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func sameTracks(a, b []repository.TrackPosition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
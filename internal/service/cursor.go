@@ -0,0 +1,91 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"song-library/internal/repository"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type cursorPayload struct {
+	Sort      string   `json:"s"`
+	Filter    string   `json:"f"`
+	Values    []string `json:"v"`
+	Backward  bool     `json:"b,omitempty"`
+	Inclusive bool     `json:"i,omitempty"`
+}
+
+// cursorCodec turns keysets into opaque tokens of the form payload.signature,
+// both base64url encoded, and rejects tokens whose HMAC does not match.
+type cursorCodec struct {
+	secret []byte
+}
+
+// cursorSecret falls back to a random per-process secret, so cursors are
+// never signed with an empty key; they then stop working on restart.
+func cursorSecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	log.Printf("CURSOR_SECRET is not set, signing cursors with a random per-process secret")
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		log.Fatalf("Failed to generate cursor secret: %v", err)
+	}
+	return random
+}
+
+// encode binds the keyset to the sort and filter it was taken from, so a
+// cursor cannot be replayed against a different query.
+func (c cursorCodec) encode(sort repository.SongSort, filter repository.SongFilter, keyset repository.Keyset) string {
+	payload, _ := json.Marshal(cursorPayload{
+		Sort:      sort.String(),
+		Filter:    filterFingerprint(filter),
+		Values:    keyset.Values,
+		Backward:  keyset.Backward,
+		Inclusive: keyset.Inclusive,
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded))
+}
+
+func (c cursorCodec) decode(sort repository.SongSort, filter repository.SongFilter, token string) (repository.Keyset, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return repository.Keyset{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(encoded)) {
+		return repository.Keyset{}, ErrInvalidCursor
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return repository.Keyset{}, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Sort != sort.String() || payload.Filter != filterFingerprint(filter) || len(payload.Values) == 0 {
+		return repository.Keyset{}, ErrInvalidCursor
+	}
+	return repository.Keyset{Values: payload.Values, Backward: payload.Backward, Inclusive: payload.Inclusive}, nil
+}
+
+func (c cursorCodec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// filterFingerprint identifies a filter by a hash of its exported fields.
+func filterFingerprint(filter repository.SongFilter) string {
+	encoded, _ := json.Marshal(filter)
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
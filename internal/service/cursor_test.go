@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"song-library/internal/repository"
+	"testing"
+)
+
+func TestCursorCodecBindsQuery(t *testing.T) {
+	byID, err := repository.ParseSongSort("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byTitle, err := repository.ParseSongSort("song")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := cursorCodec{secret: cursorSecret("")}
+	filter := repository.SongFilter{Group: "Muse"}
+	token := codec.encode(byID, filter, repository.Keyset{Values: []string{"7"}})
+
+	keyset, err := codec.decode(byID, filter, token)
+	if err != nil || len(keyset.Values) != 1 || keyset.Values[0] != "7" {
+		t.Fatalf("decode = %+v, %v, want the encoded keyset", keyset, err)
+	}
+
+	cases := map[string]struct {
+		sort   repository.SongSort
+		filter repository.SongFilter
+		token  string
+	}{
+		"other sort":    {byTitle, filter, token},
+		"other filter":  {byID, repository.SongFilter{Group: "Queen"}, token},
+		"no filter":     {byID, repository.SongFilter{}, token},
+		"bad signature": {byID, filter, token + "x"},
+		"other secret":  {byID, filter, cursorCodec{secret: cursorSecret("")}.encode(byID, filter, repository.Keyset{Values: []string{"7"}})},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := codec.decode(tc.sort, tc.filter, tc.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decode error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"song-library/internal/models"
+	"song-library/pkg/resilience"
+	"sync"
+	"time"
+)
+
+type EnrichmentWorkerConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// RateLimit is the maximum number of music-info lookups per second.
+	RateLimit float64
+}
+
+type EnrichmentStatus struct {
+	Enabled        bool       `json:"enabled"`
+	Running        bool       `json:"running"`
+	LastRunStarted *time.Time `json:"last_run_started,omitempty"`
+	LastRunEnded   *time.Time `json:"last_run_ended,omitempty"`
+	RowsScanned    int        `json:"rows_scanned"`
+	RowsFixed      int        `json:"rows_fixed"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// EnrichmentWorker periodically looks up songs that are missing lyrics, link
+// or release date and fills in whatever the music-info API knows about them.
+type EnrichmentWorker struct {
+	songService *SongService
+	cfg         EnrichmentWorkerConfig
+
+	mu     sync.Mutex
+	status EnrichmentStatus
+}
+
+func NewEnrichmentWorker(songService *SongService, cfg EnrichmentWorkerConfig) *EnrichmentWorker {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+	if cfg.RateLimit <= 0 {
+		cfg.RateLimit = 1
+	}
+	enabled := songService.MusicInfoURL != "" && cfg.Interval > 0
+	return &EnrichmentWorker{
+		songService: songService,
+		cfg:         cfg,
+		status:      EnrichmentStatus{Enabled: enabled},
+	}
+}
+
+// Start runs the worker until ctx is cancelled.
+func (w *EnrichmentWorker) Start(ctx context.Context) {
+	if !w.Status().Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *EnrichmentWorker) Status() EnrichmentStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.status
+}
+
+func (w *EnrichmentWorker) runOnce(ctx context.Context) {
+	started := time.Now()
+	w.mu.Lock()
+	w.status.Running = true
+	w.status.LastRunStarted = &started
+	w.mu.Unlock()
+
+	scanned, fixed, err := w.enrichIncomplete(ctx)
+
+	ended := time.Now()
+	w.mu.Lock()
+	w.status.Running = false
+	w.status.LastRunEnded = &ended
+	w.status.RowsScanned = scanned
+	w.status.RowsFixed = fixed
+	w.status.LastError = ""
+	if err != nil {
+		w.status.LastError = err.Error()
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Enrichment run stopped after %d rows: %v", scanned, err)
+		return
+	}
+	log.Printf("Enrichment run scanned %d rows and fixed %d", scanned, fixed)
+}
+
+func (w *EnrichmentWorker) enrichIncomplete(ctx context.Context) (scanned, fixed int, err error) {
+	limiter := time.NewTicker(time.Duration(float64(time.Second) / w.cfg.RateLimit))
+	defer limiter.Stop()
+
+	afterID := 0
+	for {
+		songs, err := w.songService.SongRepo.GetIncompleteSongs(ctx, afterID, w.cfg.BatchSize)
+		if err != nil {
+			return scanned, fixed, err
+		}
+		if len(songs) == 0 {
+			return scanned, fixed, nil
+		}
+
+		for _, song := range songs {
+			select {
+			case <-ctx.Done():
+				return scanned, fixed, ctx.Err()
+			case <-limiter.C:
+			}
+
+			scanned++
+			afterID = song.ID
+			ok, err := w.enrichSong(ctx, song)
+			if errors.Is(err, resilience.ErrCircuitOpen) {
+				return scanned, fixed, err
+			}
+			if err != nil {
+				log.Printf("Could not enrich song with ID %d: %v", song.ID, err)
+				continue
+			}
+			if ok {
+				fixed++
+			}
+		}
+	}
+}
+
+func (w *EnrichmentWorker) enrichSong(ctx context.Context, song models.Song) (bool, error) {
+	detail, err := w.songService.fetchSongDetail(ctx, song.Group, song.Song)
+	if err != nil {
+		return false, err
+	}
+
+	patch := models.Song{Lyrics: detail.Text, Link: detail.Link}
+	if detail.ReleaseDate != "" {
+		releaseDate, err := parseReleaseDate(detail.ReleaseDate)
+		if err != nil {
+			return false, err
+		}
+		patch.ReleaseDate = &releaseDate
+	}
+	return w.songService.SongRepo.FillMissingFields(ctx, song.ID, patch)
+}
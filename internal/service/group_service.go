@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"song-library/pkg/normalize"
+	"unicode/utf8"
+)
+
+// maxGroupNameLength matches the groups.name column.
+const maxGroupNameLength = 255
+
+var (
+	ErrInvalidGroupName = errors.New("invalid group name")
+	ErrInvalidMerge     = errors.New("invalid merge")
+)
+
+type GroupRequest struct {
+	Name string `json:"name"`
+}
+
+type GroupMergeRequest struct {
+	SourceIDs []int `json:"source_ids"`
+}
+
+type GroupMergeResult struct {
+	Group      *models.Group `json:"group"`
+	MovedSongs int           `json:"moved_songs"`
+}
+
+type GroupPage struct {
+	Items      []models.Group `json:"items"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	Total      int            `json:"total"`
+	TotalPages int            `json:"total_pages"`
+}
+
+type GroupService struct {
+	GroupRepo   repository.GroupStore
+	SongService *SongService
+}
+
+func NewGroupService(groupRepo repository.GroupStore, songService *SongService) *GroupService {
+	return &GroupService{GroupRepo: groupRepo, SongService: songService}
+}
+
+// groupName normalizes the requested name like the group of a song and
+// checks it fits the groups table.
+func groupName(request GroupRequest) (string, error) {
+	name := normalize.Name(request.Name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name cannot be empty", ErrInvalidGroupName)
+	}
+	if utf8.RuneCountInString(name) > maxGroupNameLength {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidGroupName, maxGroupNameLength)
+	}
+	return name, nil
+}
+
+func (s *GroupService) GetGroups(ctx context.Context, name string, page, limit int) (*GroupPage, error) {
+	groups, err := s.GroupRepo.GetGroups(ctx, name, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.GroupRepo.CountGroups(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []models.Group{}
+	}
+	return &GroupPage{
+		Items:      groups,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}, nil
+}
+
+func (s *GroupService) GetGroupByID(ctx context.Context, id int) (*models.Group, error) {
+	return s.GroupRepo.GetGroupByID(ctx, id)
+}
+
+// GetGroupSongs lists the live songs of a group with the same filtering,
+// sorting and paging as GetSongs.
+func (s *GroupService) GetGroupSongs(ctx context.Context, id int, filter repository.SongFilter, sort repository.SongSort, page, limit int) (*SongPage, error) {
+	if _, err := s.GroupRepo.GetGroupByID(ctx, id); err != nil {
+		return nil, err
+	}
+	filter.GroupID = id
+	return s.SongService.GetSongs(ctx, filter, sort, page, limit)
+}
+
+func (s *GroupService) AddGroup(ctx context.Context, request GroupRequest) (*models.Group, error) {
+	name, err := groupName(request)
+	if err != nil {
+		return nil, err
+	}
+	return s.GroupRepo.AddGroup(ctx, name)
+}
+
+func (s *GroupService) RenameGroup(ctx context.Context, id int, request GroupRequest) (*models.Group, error) {
+	name, err := groupName(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.GroupRepo.RenameGroup(ctx, id, name); err != nil {
+		return nil, err
+	}
+	return s.GroupRepo.GetGroupByID(ctx, id)
+}
+
+// DeleteGroup deletes a group. A group that still has songs is only deleted,
+// together with its songs, when force is set.
+func (s *GroupService) DeleteGroup(ctx context.Context, id int, force bool) (int, error) {
+	return s.GroupRepo.DeleteGroup(ctx, id, force)
+}
+
+// MergeGroups folds the source groups into the target group. Their songs are
+// moved to the target and their names become its aliases, so later writes
+// and group filters using an old name resolve to the target.
+func (s *GroupService) MergeGroups(ctx context.Context, targetID int, request GroupMergeRequest) (*GroupMergeResult, error) {
+	if len(request.SourceIDs) == 0 {
+		return nil, fmt.Errorf("%w: source_ids cannot be empty", ErrInvalidMerge)
+	}
+	seen := make(map[int]bool, len(request.SourceIDs))
+	var sourceIDs []int
+	for _, id := range request.SourceIDs {
+		if id == targetID {
+			return nil, fmt.Errorf("%w: a group cannot be merged into itself", ErrInvalidMerge)
+		}
+		if !seen[id] {
+			seen[id] = true
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+
+	moved, err := s.GroupRepo.MergeGroups(ctx, targetID, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.GroupRepo.GetGroupByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupMergeResult{Group: group, MovedSongs: moved}, nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"strconv"
+	"testing"
+)
+
+// mergeFixture adds one song per group named in songs and returns the
+// service with the group IDs in the same order.
+func mergeFixture(t *testing.T, songs ...models.Song) (*GroupService, []int) {
+	t.Helper()
+	store := repository.NewMemorySongRepository()
+	ids := make([]int, len(songs))
+	for i, song := range songs {
+		added, err := store.AddSong(context.Background(), song)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = added.GroupID
+	}
+	return NewGroupService(store, NewSongService(store, Config{CursorSecret: "test"})), ids
+}
+
+func TestMergeGroupsRejectsBadRequests(t *testing.T) {
+	cases := []struct {
+		name    string
+		sources func(ids []int) []int
+		wantErr error
+	}{
+		{name: "no sources", sources: func(ids []int) []int { return nil }, wantErr: ErrInvalidMerge},
+		{name: "merging a group into itself", sources: func(ids []int) []int { return []int{ids[1], ids[0]} }, wantErr: ErrInvalidMerge},
+		{name: "missing source", sources: func(ids []int) []int { return []int{ids[1], 99} }, wantErr: repository.ErrGroupNotFound},
+		{name: "shared titles", sources: func(ids []int) []int { return []int{ids[2]} }, wantErr: repository.ErrDuplicateSong},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			groups, ids := mergeFixture(t,
+				models.Song{Group: "Muse", Song: "Hysteria"},
+				models.Song{Group: "Muze", Song: "Starlight"},
+				models.Song{Group: "MUSE UK", Song: "hysteria"},
+			)
+			_, err := groups.MergeGroups(context.Background(), ids[0], GroupMergeRequest{SourceIDs: tc.sources(ids)})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			for _, id := range ids {
+				if _, err := groups.GroupRepo.GetGroupByID(context.Background(), id); err != nil {
+					t.Errorf("group %d is gone after a failed merge: %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeGroups(t *testing.T) {
+	ctx := context.Background()
+	groups, ids := mergeFixture(t,
+		models.Song{Group: "Muse", Song: "Hysteria"},
+		models.Song{Group: "Muze", Song: "Starlight"},
+		models.Song{Group: "Muse Band", Song: "Uprising"},
+	)
+	target, muze, band := ids[0], ids[1], ids[2]
+	if _, err := groups.MergeGroups(ctx, muze, GroupMergeRequest{SourceIDs: []int{band}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := groups.MergeGroups(ctx, target, GroupMergeRequest{SourceIDs: []int{muze, muze}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MovedSongs != 2 || result.Group.SongCount != 3 {
+		t.Errorf("moved %d songs into a group of %d, want 2 into a group of 3", result.MovedSongs, result.Group.SongCount)
+	}
+	if !sameFields(result.Group.Aliases, []string{"Muse Band", "Muze"}) {
+		t.Errorf("aliases = %q, want the merged group and its alias", result.Group.Aliases)
+	}
+	for _, id := range []int{muze, band} {
+		if _, err := groups.GroupRepo.GetGroupByID(ctx, id); !errors.Is(err, repository.ErrGroupNotFound) {
+			t.Errorf("group %d: got %v, want ErrGroupNotFound", id, err)
+		}
+	}
+
+	songs := groups.SongService.SongRepo
+	for id, wantVersion := range map[int]int{1: 1, 2: 2, 3: 3} {
+		song, err := songs.GetSongByID(ctx, strconv.Itoa(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if song.GroupID != target || song.Group != "Muse" || song.Version != wantVersion {
+			t.Errorf("song %d is in %q (%d) at version %d, want Muse (%d) at version %d",
+				id, song.Group, song.GroupID, song.Version, target, wantVersion)
+		}
+		revisions, err := songs.GetSongRevisions(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != wantVersion {
+			t.Errorf("song %d has %d revisions, want %d", id, len(revisions), wantVersion)
+		}
+	}
+
+	added, err := groups.SongService.AddSong(ctx, SongRequest{Group: "muse band", Song: "Starlight"}, ConflictError)
+	if !errors.Is(err, repository.ErrDuplicateSong) {
+		t.Errorf("adding a song under an alias: got %+v, %v, want ErrDuplicateSong", added, err)
+	}
+}
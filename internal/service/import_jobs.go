@@ -0,0 +1,458 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"song-library/internal/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type ImportStatus string
+
+const (
+	ImportQueued  ImportStatus = "queued"
+	ImportRunning ImportStatus = "running"
+	// ImportReady marks a finished dry run that can be committed.
+	ImportReady     ImportStatus = "ready"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+var (
+	ErrImportNotFound  = errors.New("import not found")
+	ErrImportNotReady  = errors.New("import is not a finished dry run")
+	ErrImportQueueFull = errors.New("import queue is full")
+	errImportCancelled = errors.New("import cancelled")
+)
+
+const (
+	importBatchSize     = 500
+	importQueueCapacity = 16
+)
+
+type ImportManagerConfig struct {
+	// MaxUploadBytes caps the size of an uploaded file.
+	MaxUploadBytes int64
+	// MaxJobs is the number of finished jobs kept for status and error
+	// file downloads; older ones are forgotten first.
+	MaxJobs int
+}
+
+// ImportRowError describes a row that was rejected. Line is the 1-based
+// line of the row in the upload.
+type ImportRowError struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+	Record string `json:"record"`
+}
+
+// ImportJob tracks one upload. For a dry run Created counts the rows that
+// would be created.
+type ImportJob struct {
+	ID            string         `json:"id"`
+	Format        ImportFormat   `json:"format"`
+	Mode          BulkMode       `json:"mode"`
+	OnConflict    ConflictPolicy `json:"on_conflict"`
+	DryRun        bool           `json:"dry_run"`
+	Status        ImportStatus   `json:"status"`
+	TotalRows     int            `json:"total_rows"`
+	ProcessedRows int            `json:"processed_rows"`
+	Created       int            `json:"created"`
+	Updated       int            `json:"updated"`
+	Duplicates    int            `json:"duplicates"`
+	Invalid       int            `json:"invalid"`
+	ErrorCount    int            `json:"error_count"`
+	Error         string         `json:"error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	StartedAt     *time.Time     `json:"started_at,omitempty"`
+	FinishedAt    *time.Time     `json:"finished_at,omitempty"`
+
+	rows   []importRow
+	errors []ImportRowError
+}
+
+// ImportManager runs catalog imports one at a time in the background and
+// keeps their progress and rejected rows in memory, so jobs do not survive a
+// restart.
+type ImportManager struct {
+	MaxUploadBytes int64
+
+	songService *SongService
+	cfg         ImportManagerConfig
+	queue       chan *ImportJob
+
+	mu   sync.Mutex
+	jobs map[string]*ImportJob
+}
+
+func NewImportManager(songService *SongService, cfg ImportManagerConfig) *ImportManager {
+	if cfg.MaxJobs < 1 {
+		cfg.MaxJobs = 100
+	}
+	if cfg.MaxUploadBytes < 1 {
+		cfg.MaxUploadBytes = 32 << 20
+	}
+	return &ImportManager{
+		MaxUploadBytes: cfg.MaxUploadBytes,
+		songService:    songService,
+		cfg:            cfg,
+		queue:          make(chan *ImportJob, importQueueCapacity),
+		jobs:           make(map[string]*ImportJob),
+	}
+}
+
+// Start processes queued imports until ctx is cancelled.
+func (m *ImportManager) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-m.queue:
+			m.run(ctx, job)
+		}
+	}
+}
+
+// CreateImport parses an upload and queues it. Files that cannot be parsed
+// at all, such as a CSV without a song column, fail with ErrInvalidImport
+// before a job is created; individual bad rows are reported by the job.
+func (m *ImportManager) CreateImport(data []byte, opts ImportOptions) (*ImportJob, error) {
+	rows, err := parseImport(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newImportID()
+	if err != nil {
+		return nil, err
+	}
+	job := &ImportJob{
+		ID:         id,
+		Format:     opts.Format,
+		Mode:       opts.Mode,
+		OnConflict: opts.OnConflict,
+		DryRun:     opts.DryRun,
+		Status:     ImportQueued,
+		TotalRows:  len(rows),
+		CreatedAt:  time.Now(),
+		rows:       rows,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.enqueue(job); err != nil {
+		return nil, err
+	}
+	m.jobs[job.ID] = job
+	m.evictFinished()
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// CommitImport queues a finished dry run for the real import.
+func (m *ImportManager) CommitImport(id string) (*ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrImportNotFound
+	}
+	if job.Status != ImportReady {
+		return nil, ErrImportNotReady
+	}
+
+	job.DryRun = false
+	job.Status = ImportQueued
+	if err := m.enqueue(job); err != nil {
+		job.DryRun = true
+		job.Status = ImportReady
+		return nil, err
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+func (m *ImportManager) GetImport(id string) (*ImportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrImportNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// ListImports returns the tracked jobs, newest first.
+func (m *ImportManager) ListImports() []ImportJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]ImportJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// ErrorFile renders the rejected rows of a job as CSV with the columns line,
+// reason and record.
+func (m *ImportManager) ErrorFile(id string) ([]byte, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	var rowErrors []ImportRowError
+	if ok {
+		rowErrors = append(rowErrors, job.errors...)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrImportNotFound
+	}
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Line < rowErrors[j].Line })
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"line", "reason", "record"})
+	for _, rowError := range rowErrors {
+		writer.Write([]string{strconv.Itoa(rowError.Line), rowError.Reason, rowError.Record})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("could not write error file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// enqueue must be called with m.mu held.
+func (m *ImportManager) enqueue(job *ImportJob) error {
+	select {
+	case m.queue <- job:
+		return nil
+	default:
+		return ErrImportQueueFull
+	}
+}
+
+// evictFinished drops the oldest finished jobs beyond MaxJobs. It must be
+// called with m.mu held.
+func (m *ImportManager) evictFinished() {
+	var finished []*ImportJob
+	for _, job := range m.jobs {
+		if job.Status == ImportCompleted || job.Status == ImportFailed || job.Status == ImportReady {
+			finished = append(finished, job)
+		}
+	}
+	excess := len(m.jobs) - m.cfg.MaxJobs
+	if excess <= 0 {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].CreatedAt.Before(finished[j].CreatedAt) })
+	for i := 0; i < excess && i < len(finished); i++ {
+		delete(m.jobs, finished[i].ID)
+	}
+}
+
+func (m *ImportManager) run(ctx context.Context, job *ImportJob) {
+	started := time.Now()
+	m.update(func() {
+		job.Status = ImportRunning
+		job.StartedAt = &started
+		job.FinishedAt = nil
+		job.ProcessedRows = 0
+		job.Created, job.Updated, job.Duplicates, job.Invalid = 0, 0, 0, 0
+		job.errors = nil
+	})
+
+	var err error
+	if job.DryRun {
+		err = m.dryRun(ctx, job)
+	} else {
+		err = m.importRows(ctx, job)
+	}
+
+	finished := time.Now()
+	var processed, total, created, duplicates, invalid int
+	m.update(func() {
+		processed, total = job.ProcessedRows, job.TotalRows
+		created, duplicates, invalid = job.Created, job.Duplicates, job.Invalid
+		job.FinishedAt = &finished
+		job.ErrorCount = len(job.errors)
+		switch {
+		case err != nil:
+			job.Status = ImportFailed
+			job.Error = err.Error()
+		case job.DryRun:
+			job.Status = ImportReady
+		default:
+			job.Status = ImportCompleted
+			job.rows = nil
+		}
+	})
+	// The counters are copied under the lock, as status requests read them
+	// concurrently.
+	if err != nil {
+		log.Printf("Import %s failed after %d rows: %v", job.ID, processed, err)
+		return
+	}
+	log.Printf("Import %s processed %d rows: %d created, %d duplicates, %d invalid",
+		job.ID, total, created, duplicates, invalid)
+}
+
+// dryRun validates every row and reports duplicates within the file and of
+// songs already in the library, counting what the import would do without
+// writing.
+func (m *ImportManager) dryRun(ctx context.Context, job *ImportJob) error {
+	rows := m.checkRows(job)
+	m.update(func() { job.ProcessedRows = job.TotalRows - len(rows) })
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		if ctx.Err() != nil {
+			return errImportCancelled
+		}
+		batch := rows[start:min(start+importBatchSize, len(rows))]
+		songs := make([]models.Song, len(batch))
+		for i, row := range batch {
+			request := row.Item.Request
+			normalizeSongRequest(&request)
+			songs[i] = models.Song{Group: request.Group, Song: request.Song}
+		}
+
+		existing, err := m.songService.SongRepo.FindDuplicateSongs(ctx, songs)
+		if err != nil {
+			return fmt.Errorf("failed to look up existing songs: %w", err)
+		}
+		m.update(func() {
+			job.ProcessedRows += len(batch)
+			for i, id := range existing {
+				switch {
+				case id == 0:
+					job.Created++
+				case job.OnConflict == ConflictUpdate:
+					job.Updated++
+				default:
+					job.Duplicates++
+					job.errors = append(job.errors, ImportRowError{
+						Line: batch[i].Line, Reason: fmt.Sprintf("duplicate of song %d", id), Record: batch[i].Record,
+					})
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// checkRows validates every row and rejects repeats of an earlier row,
+// recording both as row errors. It returns the rows that passed.
+func (m *ImportManager) checkRows(job *ImportJob) []importRow {
+	var accepted []importRow
+	var rowErrors []ImportRowError
+	invalid, duplicates := 0, 0
+	firstSeen := make(map[[2]string]int)
+	for _, row := range job.rows {
+		err := row.Item.Err
+		if err == nil {
+			request := row.Item.Request
+			normalizeSongRequest(&request)
+			err = m.songService.ValidateSongRequest(request)
+		}
+		if err != nil {
+			invalid++
+			rowErrors = append(rowErrors, ImportRowError{Line: row.Line, Reason: err.Error(), Record: row.Record})
+			continue
+		}
+
+		key := songIdentity(row.Item.Request)
+		if first, ok := firstSeen[key]; ok {
+			duplicates++
+			rowErrors = append(rowErrors, ImportRowError{
+				Line: row.Line, Reason: fmt.Sprintf("duplicate of line %d", first), Record: row.Record,
+			})
+			continue
+		}
+		firstSeen[key] = row.Line
+		accepted = append(accepted, row)
+	}
+
+	m.update(func() {
+		job.Invalid += invalid
+		job.Duplicates += duplicates
+		job.errors = append(job.errors, rowErrors...)
+	})
+	return accepted
+}
+
+// importRows inserts the rows that pass checkRows in batches, updating
+// progress after each one. In all-or-nothing mode any rejected row aborts
+// the import and the remaining rows go in as a single batch.
+func (m *ImportManager) importRows(ctx context.Context, job *ImportJob) error {
+	rows := m.checkRows(job)
+	m.update(func() { job.ProcessedRows = job.TotalRows - len(rows) })
+
+	batchSize := importBatchSize
+	if job.Mode == BulkAllOrNothing {
+		if job.Invalid > 0 || (job.OnConflict == ConflictError && job.Duplicates > 0) {
+			return fmt.Errorf("import rejected: %d invalid and %d duplicate rows", job.Invalid, job.Duplicates)
+		}
+		batchSize = max(len(rows), 1)
+	}
+
+	for start := 0; start < len(rows); start += batchSize {
+		if ctx.Err() != nil {
+			return errImportCancelled
+		}
+		batch := rows[start:min(start+batchSize, len(rows))]
+		items := make([]BulkItem, len(batch))
+		for i, row := range batch {
+			items[i] = row.Item
+		}
+
+		result, err := m.songService.BulkAddSongs(ctx, items, job.Mode, job.OnConflict)
+		if err != nil {
+			return err
+		}
+		m.update(func() {
+			job.ProcessedRows += len(batch)
+			job.Created += result.Created
+			job.Updated += result.Updated
+			job.Duplicates += result.Duplicates
+			for _, item := range result.Items {
+				if item.Status == BulkDuplicate {
+					row := batch[item.Index]
+					job.errors = append(job.errors, ImportRowError{Line: row.Line, Reason: item.Reason, Record: row.Record})
+				}
+			}
+		})
+		if !result.Committed {
+			return fmt.Errorf("import rejected: %d rows duplicate existing songs", result.Duplicates)
+		}
+	}
+	return nil
+}
+
+// update runs fn with the job table locked, so progress is never read half
+// written.
+func (m *ImportManager) update(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn()
+}
+
+func newImportID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate import id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"strings"
+	"testing"
+)
+
+func TestDryRunReportsExistingSongs(t *testing.T) {
+	upload := "group,song\nMuse,Hysteria\nmuse,  HYSTERIA\nMuse,Uprising\nQueen,\nQueen,Innuendo\n"
+	cases := []struct {
+		name           string
+		onConflict     ConflictPolicy
+		wantCreated    int
+		wantUpdated    int
+		wantDuplicates int
+		wantReasons    []string
+	}{
+		{
+			name:           "existing songs are duplicates",
+			onConflict:     ConflictError,
+			wantCreated:    2,
+			wantDuplicates: 2,
+			wantReasons:    []string{"duplicate of song 1", "duplicate of line 2"},
+		},
+		{
+			name:           "existing songs would be updated",
+			onConflict:     ConflictUpdate,
+			wantCreated:    2,
+			wantUpdated:    1,
+			wantDuplicates: 1,
+			wantReasons:    []string{"duplicate of line 2"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemorySongRepository()
+			if _, err := store.AddSong(ctx, models.Song{Group: "Muse", Song: "Hysteria"}); err != nil {
+				t.Fatal(err)
+			}
+			manager := NewImportManager(NewSongService(store, Config{CursorSecret: "test"}), ImportManagerConfig{})
+
+			job, err := manager.CreateImport([]byte(upload), ImportOptions{
+				Format: ImportCSV, DryRun: true, Mode: BulkBestEffort, OnConflict: tc.onConflict,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			manager.run(ctx, <-manager.queue)
+
+			job, err = manager.GetImport(job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != ImportReady || job.ProcessedRows != 5 {
+				t.Fatalf("job is %s after %d rows, want it ready after 5", job.Status, job.ProcessedRows)
+			}
+			if job.Created != tc.wantCreated || job.Updated != tc.wantUpdated || job.Duplicates != tc.wantDuplicates || job.Invalid != 1 {
+				t.Errorf("got %d created, %d updated, %d duplicates, %d invalid, want %d, %d, %d, 1",
+					job.Created, job.Updated, job.Duplicates, job.Invalid, tc.wantCreated, tc.wantUpdated, tc.wantDuplicates)
+			}
+
+			errorFile, err := manager.ErrorFile(job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, reason := range tc.wantReasons {
+				if !strings.Contains(string(errorFile), reason) {
+					t.Errorf("error file %q does not mention %q", errorFile, reason)
+				}
+			}
+			if songs, _ := store.CountSongs(ctx, repository.SongFilter{}); songs != 1 {
+				t.Errorf("dry run left %d songs, want 1", songs)
+			}
+		})
+	}
+}
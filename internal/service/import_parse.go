@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// importFields are the song fields a CSV column can be mapped to.
+var importFields = []string{"group", "song", "release_date", "lyrics", "link"}
+
+type ImportOptions struct {
+	Format ImportFormat
+	// Delimiter separates CSV fields; it defaults to a comma.
+	Delimiter rune
+	// Mapping maps CSV header names to song fields. Headers missing from it
+	// are matched to fields of the same name, case-insensitively.
+	Mapping    map[string]string
+	DryRun     bool
+	Mode       BulkMode
+	OnConflict ConflictPolicy
+}
+
+// importRow is one parsed record of an upload. Line is its 1-based line in
+// the file and Record the raw text reported back in the error file.
+type importRow struct {
+	Line   int
+	Record string
+	Item   BulkItem
+}
+
+func parseImport(data []byte, opts ImportOptions) ([]importRow, error) {
+	switch opts.Format {
+	case ImportCSV:
+		return parseCSVImport(data, opts)
+	case ImportNDJSON:
+		return parseNDJSONImport(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidImport, opts.Format)
+	}
+}
+
+func parseCSVImport(data []byte, opts ImportOptions) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not read header: %v", ErrInvalidImport, err)
+	}
+	columns, err := mapColumns(header, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+			}
+			rows = append(rows, importRow{
+				Line: parseErr.StartLine,
+				Item: BulkItem{Err: fmt.Errorf("malformed row: %v", parseErr.Err)},
+			})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, importRow{
+			Line:   line,
+			Record: csvRecord(record, reader.Comma),
+			Item:   csvItem(record, columns),
+		})
+	}
+}
+
+// csvRecord encodes a parsed record back into a CSV line with the file's
+// delimiter, quoting fields that need it, for the error file.
+func csvRecord(record []string, comma rune) string {
+	var line strings.Builder
+	writer := csv.NewWriter(&line)
+	writer.Comma = comma
+	writer.Write(record)
+	writer.Flush()
+	return strings.TrimSuffix(line.String(), "\n")
+}
+
+// mapColumns resolves each song field to its CSV column index, or -1 when
+// no column provides it.
+func mapColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	lowered := make(map[string]string, len(mapping))
+	for name, field := range mapping {
+		lowered[strings.ToLower(strings.TrimSpace(name))] = field
+	}
+
+	columns := make(map[string]int, len(importFields))
+	for _, field := range importFields {
+		columns[field] = -1
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		field, ok := lowered[name]
+		if !ok {
+			field = name
+		}
+		index, known := columns[field]
+		if !known {
+			if ok {
+				return nil, fmt.Errorf("%w: column %q is mapped to unknown field %q", ErrInvalidImport, name, field)
+			}
+			continue
+		}
+		if index >= 0 {
+			return nil, fmt.Errorf("%w: more than one column maps to %q", ErrInvalidImport, field)
+		}
+		columns[field] = i
+	}
+	if columns["song"] < 0 {
+		return nil, fmt.Errorf("%w: no column maps to song", ErrInvalidImport)
+	}
+	return columns, nil
+}
+
+func csvItem(record []string, columns map[string]int) BulkItem {
+	value := func(field string) string {
+		if index := columns[field]; index >= 0 && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	item := BulkItem{Request: SongRequest{
+		Group:  value("group"),
+		Song:   value("song"),
+		Lyrics: value("lyrics"),
+		Link:   value("link"),
+	}}
+	if releaseDate := value("release_date"); releaseDate != "" {
+		parsed, err := parseReleaseDate(releaseDate)
+		if err != nil {
+			item.Err = err
+			return item
+		}
+		item.Request.ReleaseDate = parsed
+	}
+	return item
+}
+
+func parseNDJSONImport(data []byte) ([]importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := importRow{Line: line, Record: text}
+		if err := json.Unmarshal([]byte(text), &row.Item.Request); err != nil {
+			row.Item.Err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestCSVRecordRoundTrips(t *testing.T) {
+	cases := []struct {
+		name   string
+		record []string
+		comma  rune
+		want   string
+	}{
+		{name: "plain", record: []string{"Muse", "Hysteria"}, comma: ',', want: "Muse,Hysteria"},
+		{name: "delimiter in a field", record: []string{"Crosby, Stills & Nash", "Helplessly Hoping"}, comma: ',', want: `"Crosby, Stills & Nash",Helplessly Hoping`},
+		{name: "quotes in a field", record: []string{"Muse", `Say "Hi"`}, comma: ';', want: `Muse;"Say ""Hi"""`},
+		{name: "newline in a field", record: []string{"Muse", "line one\nline two"}, comma: '\t', want: "Muse\t\"line one\nline two\""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			line := csvRecord(tc.record, tc.comma)
+			if line != tc.want {
+				t.Errorf("got %q, want %q", line, tc.want)
+			}
+			reader := csv.NewReader(strings.NewReader(line))
+			reader.Comma = tc.comma
+			parsed, err := reader.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameFields(parsed, tc.record) {
+				t.Errorf("read back %q, want %q", parsed, tc.record)
+			}
+		})
+	}
+}
+
+func sameFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
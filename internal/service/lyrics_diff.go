@@ -0,0 +1,101 @@
+package service
+
+import "strings"
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// lyricsLines splits lyrics into lines, treating the escaped "\n" sequences
+// found in seeded rows like real line breaks.
+func lyricsLines(lyrics string) []string {
+	lyrics = strings.ReplaceAll(lyrics, "\\n", "\n")
+	if lyrics == "" {
+		return nil
+	}
+	return strings.Split(lyrics, "\n")
+}
+
+// maxLyricsDiffCells caps the LCS table diffLyrics builds for the lines
+// that differ, so a revision of huge lyrics cannot exhaust memory.
+const maxLyricsDiffCells = 1 << 20
+
+// diffLyrics returns a line-level diff turning before into after, based on
+// their longest common subsequence of lines. It reports false when the
+// changed part of the lyrics is too large to diff.
+func diffLyrics(before, after string) ([]DiffLine, bool) {
+	a, b := lyricsLines(before), lyricsLines(after)
+
+	// Lines shared at the start and end are kept out of the table.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > maxLyricsDiffCells {
+		return []DiffLine{}, false
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	diff = appendLCSDiff(diff, midA, midB)
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return diff, true
+}
+
+func appendLCSDiff(diff []DiffLine, a, b []string) []DiffLine {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return diff
+}
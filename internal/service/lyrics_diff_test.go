@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLyrics(t *testing.T) {
+	cases := []struct {
+		name   string
+		before string
+		after  string
+		want   []DiffLine
+	}{
+		{name: "empty lyrics", want: []DiffLine{}},
+		{
+			name:  "first revision",
+			after: "Ooh baby\nDon't you know I suffer",
+			want:  []DiffLine{{DiffInsert, "Ooh baby"}, {DiffInsert, "Don't you know I suffer"}},
+		},
+		{
+			name:   "escaped line breaks from seeded rows",
+			before: `Ooh baby\nDon't you know I suffer`,
+			after:  "Ooh baby\nDon't you know I suffer",
+			want:   []DiffLine{{DiffEqual, "Ooh baby"}, {DiffEqual, "Don't you know I suffer"}},
+		},
+		{
+			name:   "pure insert",
+			before: "one\nthree",
+			after:  "one\ntwo\nthree",
+			want:   []DiffLine{{DiffEqual, "one"}, {DiffInsert, "two"}, {DiffEqual, "three"}},
+		},
+		{
+			name:   "pure delete",
+			before: "one\ntwo\nthree",
+			after:  "one\nthree",
+			want:   []DiffLine{{DiffEqual, "one"}, {DiffDelete, "two"}, {DiffEqual, "three"}},
+		},
+		{
+			name:   "changed lines",
+			before: "one\ntwo\nthree\nfour",
+			after:  "one\n2\n3\nfour",
+			want: []DiffLine{
+				{DiffEqual, "one"},
+				{DiffDelete, "two"},
+				{DiffDelete, "three"},
+				{DiffInsert, "2"},
+				{DiffInsert, "3"},
+				{DiffEqual, "four"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := diffLyrics(tc.before, tc.after)
+			if !ok {
+				t.Fatal("diff reported as too large")
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("got %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffLyricsTooLarge(t *testing.T) {
+	var before, after strings.Builder
+	for i := 0; i < 2000; i++ {
+		before.WriteString("a\n")
+		after.WriteString("b\n")
+	}
+	if diff, ok := diffLyrics("same\n"+before.String(), "same\n"+after.String()); ok || len(diff) != 0 {
+		t.Errorf("got %d lines, ok = %v, want no diff", len(diff), ok)
+	}
+	if _, ok := diffLyrics(before.String()+"x", before.String()+"y"); !ok {
+		t.Error("lyrics differing in one line were reported as too large")
+	}
+}
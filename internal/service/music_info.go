@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrValidation = errors.New("validation failed")
+	ErrEnrichment = errors.New("enrichment failed")
+
+	// errUpstreamUnavailable marks failures where the music-info API is down or
+	// flapping; songs are then saved without enrichment instead of rejected.
+	errUpstreamUnavailable = errors.New("music info api unavailable")
+	// errNoSongDetail marks a 4xx from the music-info API: it has nothing on
+	// the song, which is then saved without enrichment too.
+	errNoSongDetail = errors.New("music info api has no details for the song")
+)
+
+// musicInfoDateLayouts lists the release date formats accepted from the music-info API.
+var musicInfoDateLayouts = []string{"02.01.2006", "2006-01-02", time.RFC3339}
+
+type SongDetail struct {
+	ReleaseDate string `json:"releaseDate"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+func needsEnrichment(songRequest SongRequest) bool {
+	return songRequest.ReleaseDate.IsZero() && songRequest.Lyrics == "" && songRequest.Link == ""
+}
+
+func (s *SongService) fetchSongDetail(ctx context.Context, group, song string) (*SongDetail, error) {
+	endpoint, err := url.Parse(strings.TrimRight(s.MusicInfoURL, "/") + "/info")
+	if err != nil {
+		return nil, fmt.Errorf("invalid music info url: %w", err)
+	}
+	query := endpoint.Query()
+	query.Set("group", group)
+	query.Set("song", song)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: status %d", errUpstreamUnavailable, resp.StatusCode)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: status %d", errNoSongDetail, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("music info api responded with status %d", resp.StatusCode)
+	}
+
+	// A body cut short by a timeout or the connection is the API being
+	// unavailable; only a body that arrived in full can be malformed.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading song details: %w", errUpstreamUnavailable, err)
+	}
+	var detail SongDetail
+	if err := json.Unmarshal(body, &detail); err != nil {
+		return nil, fmt.Errorf("error decoding song details: %w", err)
+	}
+	return &detail, nil
+}
+
+func (s *SongService) enrichSongRequest(ctx context.Context, songRequest *SongRequest) error {
+	detail, err := s.fetchSongDetail(ctx, songRequest.Group, songRequest.Song)
+	if err != nil {
+		return err
+	}
+
+	if detail.ReleaseDate != "" {
+		releaseDate, err := parseReleaseDate(detail.ReleaseDate)
+		if err != nil {
+			return err
+		}
+		songRequest.ReleaseDate = releaseDate
+	}
+	songRequest.Lyrics = detail.Text
+	songRequest.Link = detail.Link
+	return nil
+}
+
+func parseReleaseDate(value string) (time.Time, error) {
+	for _, layout := range musicInfoDateLayouts {
+		if releaseDate, err := time.Parse(layout, value); err == nil {
+			return releaseDate, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized release date %q", value)
+}
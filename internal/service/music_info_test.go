@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"song-library/pkg/resilience"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// musicInfoServer serves every /info request with status and body.
+func musicInfoServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" || r.URL.Query().Get("group") != "Muse" || r.URL.Query().Get("song") != "Hysteria" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func musicInfoService(url string) *SongService {
+	return NewSongService(repository.NewMemorySongRepository(), Config{
+		MusicInfo: MusicInfoConfig{
+			URL:        url,
+			Resilience: resilience.Config{FailureThreshold: 100, OpenTimeout: time.Second},
+		},
+		CursorSecret: "test",
+	})
+}
+
+func TestFetchSongDetail(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		want    *SongDetail
+		wantErr error
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"releaseDate": "16.07.2006", "text": "It's bugging me", "link": "https://example.com/hysteria"}`,
+			want:   &SongDetail{ReleaseDate: "16.07.2006", Text: "It's bugging me", Link: "https://example.com/hysteria"},
+		},
+		{name: "server error", status: http.StatusBadGateway, body: `{}`, wantErr: errUpstreamUnavailable},
+		{name: "not found", status: http.StatusNotFound, body: `{"error": "unknown song"}`, wantErr: errNoSongDetail},
+		{name: "bad request", status: http.StatusBadRequest, body: `{}`, wantErr: errNoSongDetail},
+		{name: "unexpected status", status: http.StatusNoContent},
+		{name: "malformed body", status: http.StatusOK, body: `{"text": `},
+		{name: "wrong body type", status: http.StatusOK, body: `["Hysteria"]`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := musicInfoService(musicInfoServer(t, tc.status, tc.body).URL)
+			detail, err := s.fetchSongDetail(context.Background(), "Muse", "Hysteria")
+			if tc.want != nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *detail != *tc.want {
+					t.Errorf("got %+v, want %+v", detail, tc.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got %+v, want an error", detail)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && (errors.Is(err, errUpstreamUnavailable) || errors.Is(err, errNoSongDetail)) {
+				t.Errorf("got %v, want an error that rejects the song", err)
+			}
+		})
+	}
+}
+
+func TestAddSongEnrichment(t *testing.T) {
+	cases := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    error
+		wantLyrics string
+	}{
+		{
+			name:       "details are filled in",
+			status:     http.StatusOK,
+			body:       `{"releaseDate": "2006-07-16", "text": "It's bugging me", "link": "https://example.com/hysteria"}`,
+			wantLyrics: "It's bugging me",
+		},
+		{name: "unknown song is saved as is", status: http.StatusNotFound, body: `{}`},
+		{name: "unavailable api is saved as is", status: http.StatusServiceUnavailable, body: `{}`},
+		{name: "malformed body rejects the song", status: http.StatusOK, body: `not json`, wantErr: ErrEnrichment},
+		{name: "bad release date rejects the song", status: http.StatusOK, body: `{"releaseDate": "someday"}`, wantErr: ErrEnrichment},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := musicInfoService(musicInfoServer(t, tc.status, tc.body).URL)
+			result, err := s.AddSong(context.Background(), SongRequest{Group: "Muse", Song: "Hysteria"}, ConflictError)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := s.SongRepo.GetSongByID(context.Background(), strconv.Itoa(result.Song.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Lyrics != tc.wantLyrics {
+				t.Errorf("lyrics = %q, want %q", stored.Lyrics, tc.wantLyrics)
+			}
+		})
+	}
+}
+
+func TestFetchSongDetailBodyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"text": `))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := NewSongService(repository.NewMemorySongRepository(), Config{
+		MusicInfo: MusicInfoConfig{
+			URL:        server.URL,
+			Resilience: resilience.Config{AttemptTimeout: 50 * time.Millisecond, FailureThreshold: 100, OpenTimeout: time.Second},
+		},
+		CursorSecret: "test",
+	})
+	if _, err := s.fetchSongDetail(context.Background(), "Muse", "Hysteria"); !errors.Is(err, errUpstreamUnavailable) {
+		t.Errorf("got %v, want errUpstreamUnavailable", err)
+	}
+}
+
+func TestAddSongSkipsEnrichingDuplicates(t *testing.T) {
+	cases := []struct {
+		name       string
+		onConflict ConflictPolicy
+		wantErr    error
+		wantStatus AddSongStatus
+		wantCalls  int32
+	}{
+		{name: "rejected duplicate", onConflict: ConflictError, wantErr: repository.ErrDuplicateSong},
+		{name: "ignored duplicate", onConflict: ConflictIgnore, wantStatus: SongIgnored},
+		{name: "updated duplicate", onConflict: ConflictUpdate, wantStatus: SongUpdated, wantCalls: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.Write([]byte(`{"text": "It's bugging me"}`))
+			}))
+			defer server.Close()
+			s := musicInfoService(server.URL)
+			if _, err := s.SongRepo.AddSong(context.Background(), models.Song{Group: "Muse", Song: "Hysteria"}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := s.AddSong(context.Background(), SongRequest{Group: "muse", Song: "HYSTERIA"}, tc.onConflict)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got %v, want %v", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if result.Status != tc.wantStatus {
+				t.Errorf("status = %s, want %s", result.Status, tc.wantStatus)
+			}
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("music info api called %d times, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"song-library/pkg/normalize"
+)
+
+// normalizeSongRequest normalizes the group and song names of a request
+// before it is validated and written: Unicode NFC, smart quotes folded to
+// ASCII and runs of whitespace collapsed. Lyrics keep their formatting.
+func normalizeSongRequest(request *SongRequest) {
+	request.Group = normalize.Name(request.Group)
+	request.Song = normalize.Name(request.Song)
+}
+
+// songIdentity is what makes two requests the same song within a batch: the
+// name keys of the group and the title, as compared by the stores.
+func songIdentity(request SongRequest) [2]string {
+	return [2]string{normalize.Key(request.Group), normalize.Key(request.Song)}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+)
+
+type BulkMode string
+
+const (
+	// BulkBestEffort inserts every valid, non-duplicate song.
+	BulkBestEffort BulkMode = "best_effort"
+	// BulkAllOrNothing inserts nothing unless every song can be inserted.
+	BulkAllOrNothing BulkMode = "all_or_nothing"
+)
+
+type BulkItemStatus string
+
+const (
+	BulkCreated   BulkItemStatus = "created"
+	BulkUpdated   BulkItemStatus = "updated"
+	BulkDuplicate BulkItemStatus = "duplicate"
+	BulkInvalid   BulkItemStatus = "invalid"
+	// BulkSkipped marks valid songs left out because an all-or-nothing
+	// batch was rejected.
+	BulkSkipped BulkItemStatus = "skipped"
+)
+
+// BulkItem is one entry of a bulk request. Err is set when the entry could
+// not be decoded.
+type BulkItem struct {
+	Request SongRequest
+	Err     error
+}
+
+type BulkItemResult struct {
+	Index       int            `json:"index"`
+	Status      BulkItemStatus `json:"status"`
+	ID          int            `json:"id,omitempty"`
+	DuplicateOf int            `json:"duplicate_of,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+}
+
+// BulkResult reports the outcome of every item in request order. Committed
+// is false when an all-or-nothing batch was rejected.
+type BulkResult struct {
+	Mode       BulkMode         `json:"mode"`
+	OnConflict ConflictPolicy   `json:"on_conflict"`
+	Committed  bool             `json:"committed"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	Duplicates int              `json:"duplicates"`
+	Invalid    int              `json:"invalid"`
+	Items      []BulkItemResult `json:"items"`
+}
+
+// BulkAddSongs validates and inserts many songs at once. Unlike AddSong it
+// does not call the music-info API; incomplete songs are picked up by the
+// enrichment worker instead.
+//
+// Songs duplicating a live one are reported as duplicates, or replace it
+// when onConflict is ConflictUpdate. Repeats within the batch are always
+// reported as duplicates of their first occurrence. Only with ConflictError
+// do duplicates reject an all-or-nothing batch.
+func (s *SongService) BulkAddSongs(ctx context.Context, items []BulkItem, mode BulkMode, onConflict ConflictPolicy) (*BulkResult, error) {
+	result := &BulkResult{Mode: mode, OnConflict: onConflict, Items: make([]BulkItemResult, len(items))}
+
+	var songs []models.Song
+	var positions []int
+	firstSeen := make(map[[2]string]int)
+	for i, item := range items {
+		result.Items[i].Index = i
+		normalizeSongRequest(&item.Request)
+		err := item.Err
+		if err == nil {
+			err = s.ValidateSongRequest(item.Request)
+		}
+		if err != nil {
+			result.Items[i].Status = BulkInvalid
+			result.Items[i].Reason = err.Error()
+			result.Invalid++
+			continue
+		}
+
+		key := songIdentity(item.Request)
+		if first, ok := firstSeen[key]; ok {
+			result.Items[i].Status = BulkDuplicate
+			result.Items[i].Reason = fmt.Sprintf("duplicate of item %d", first)
+			result.Duplicates++
+			continue
+		}
+		firstSeen[key] = i
+
+		releaseDate := item.Request.ReleaseDate
+		songs = append(songs, models.Song{
+			Group:       item.Request.Group,
+			Song:        item.Request.Song,
+			ReleaseDate: &releaseDate,
+			Lyrics:      item.Request.Lyrics,
+			Link:        item.Request.Link,
+		})
+		positions = append(positions, i)
+	}
+
+	allOrNothing := mode == BulkAllOrNothing
+	rejectDuplicates := allOrNothing && onConflict == ConflictError
+	rejected := func() bool {
+		return allOrNothing && (result.Invalid > 0 || (rejectDuplicates && result.Duplicates > 0))
+	}
+	if len(songs) > 0 && !rejected() {
+		outcomes, err := s.SongRepo.AddSongs(ctx, songs, repository.BulkOptions{
+			AllOrNothing:     rejectDuplicates,
+			UpdateDuplicates: onConflict == ConflictUpdate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save songs: %w", err)
+		}
+		for j, outcome := range outcomes {
+			item := &result.Items[positions[j]]
+			switch {
+			case outcome.Updated:
+				item.Status = BulkUpdated
+				item.ID = outcome.ID
+				result.Updated++
+			case outcome.ID != 0:
+				item.Status = BulkCreated
+				item.ID = outcome.ID
+				result.Created++
+			case outcome.DuplicateOf != 0:
+				item.Status = BulkDuplicate
+				item.DuplicateOf = outcome.DuplicateOf
+				item.Reason = "song already exists"
+				result.Duplicates++
+			}
+		}
+	}
+
+	for i := range result.Items {
+		if result.Items[i].Status == "" {
+			result.Items[i].Status = BulkSkipped
+		}
+	}
+	result.Committed = !rejected()
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import "song-library/internal/models"
+
+// ConflictPolicy decides what a write does with a song that has the same
+// group and title as a live song already in the library.
+type ConflictPolicy string
+
+const (
+	// ConflictError rejects the song with a *repository.DuplicateSongError.
+	ConflictError ConflictPolicy = "error"
+	// ConflictIgnore keeps the existing song and drops the new one.
+	ConflictIgnore ConflictPolicy = "ignore"
+	// ConflictUpdate replaces the existing song with the new one.
+	ConflictUpdate ConflictPolicy = "update"
+)
+
+// ConflictPolicies lists the accepted values of the on_conflict parameter.
+var ConflictPolicies = []ConflictPolicy{ConflictError, ConflictIgnore, ConflictUpdate}
+
+func (p ConflictPolicy) Valid() bool {
+	for _, policy := range ConflictPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+type AddSongStatus string
+
+const (
+	SongCreated AddSongStatus = "created"
+	SongIgnored AddSongStatus = "ignored"
+	SongUpdated AddSongStatus = "updated"
+)
+
+// AddSongResult holds the song a POST ended up at as stored: a new one, or
+// the existing song it duplicated when the conflict policy is ignore or update.
+// Status tells which, for the handler to pick the response code.
+type AddSongResult struct {
+	Song   *models.Song
+	Status AddSongStatus
+}
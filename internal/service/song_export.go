@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"strconv"
+	"time"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+	ExportJSON   ExportFormat = "json"
+)
+
+// exportFlushRows is how many songs are buffered before they are pushed to
+// the client.
+const exportFlushRows = 500
+
+type ExportOptions struct {
+	Format     ExportFormat
+	Filter     repository.SongFilter
+	Sort       repository.SongSort
+	OmitLyrics bool
+}
+
+// exportedSong is the JSON and NDJSON representation of a song. It decodes
+// as a SongRequest, so exports can be fed back into bulk ingestion and
+// imports.
+type exportedSong struct {
+	ID          int        `json:"id"`
+	Group       string     `json:"group"`
+	Song        string     `json:"song"`
+	ReleaseDate *time.Time `json:"release_date"`
+	Lyrics      *string    `json:"lyrics,omitempty"`
+	Link        string     `json:"link"`
+}
+
+// songEncoder writes one export format. begin runs before the first song and
+// end after the last one, even when no song matched.
+type songEncoder interface {
+	begin() error
+	encode(song models.Song) error
+	end() error
+}
+
+// ExportSongs streams every song matching the options to w. Output is
+// buffered and flushed every exportFlushRows songs; when w is an
+// http.Flusher each flush is sent to the client as it happens. A failing
+// query is reported before anything is written to w.
+func (s *SongService) ExportSongs(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	buffered := bufio.NewWriterSize(w, 64*1024)
+	encoder := newSongEncoder(buffered, opts)
+	flush := func() error {
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := encoder.begin(); err != nil {
+		return err
+	}
+	count := 0
+	err := s.SongRepo.StreamSongs(ctx, opts.Filter, opts.Sort, opts.OmitLyrics, func(song models.Song) error {
+		if err := encoder.encode(song); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := encoder.end(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func newSongEncoder(w *bufio.Writer, opts ExportOptions) songEncoder {
+	switch opts.Format {
+	case ExportCSV:
+		return &csvSongEncoder{writer: csv.NewWriter(w), omitLyrics: opts.OmitLyrics}
+	case ExportJSON:
+		return &jsonSongEncoder{w: w, omitLyrics: opts.OmitLyrics, array: true}
+	default:
+		return &jsonSongEncoder{w: w, omitLyrics: opts.OmitLyrics}
+	}
+}
+
+// csvSongEncoder writes a header row named after the import fields, so the
+// file can be imported again without a column mapping.
+type csvSongEncoder struct {
+	writer     *csv.Writer
+	omitLyrics bool
+}
+
+func (e *csvSongEncoder) begin() error {
+	header := []string{"id", "group", "song", "release_date", "lyrics", "link"}
+	if e.omitLyrics {
+		header = []string{"id", "group", "song", "release_date", "link"}
+	}
+	return e.writer.Write(header)
+}
+
+func (e *csvSongEncoder) encode(song models.Song) error {
+	releaseDate := ""
+	if song.ReleaseDate != nil && !song.ReleaseDate.IsZero() {
+		releaseDate = song.ReleaseDate.Format("2006-01-02")
+	}
+	record := []string{strconv.Itoa(song.ID), song.Group, song.Song, releaseDate}
+	if !e.omitLyrics {
+		record = append(record, song.Lyrics)
+	}
+	record = append(record, song.Link)
+	if err := e.writer.Write(record); err != nil {
+		return err
+	}
+	// csv.Writer buffers on its own; hand each record to the shared buffer so
+	// flushes see it.
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvSongEncoder) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonSongEncoder writes one object per line, or a JSON array with one
+// element per line when array is set.
+type jsonSongEncoder struct {
+	w          *bufio.Writer
+	omitLyrics bool
+	array      bool
+	count      int
+}
+
+func (e *jsonSongEncoder) begin() error {
+	if e.array {
+		_, err := e.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (e *jsonSongEncoder) encode(song models.Song) error {
+	exported := exportedSong{
+		ID:          song.ID,
+		Group:       song.Group,
+		Song:        song.Song,
+		ReleaseDate: song.ReleaseDate,
+		Link:        song.Link,
+	}
+	if song.ReleaseDate != nil && song.ReleaseDate.IsZero() {
+		exported.ReleaseDate = nil
+	}
+	if !e.omitLyrics {
+		exported.Lyrics = &song.Lyrics
+	}
+	data, err := json.Marshal(exported)
+	if err != nil {
+		return err
+	}
+
+	if e.array {
+		separator := "\n"
+		if e.count > 0 {
+			separator = ",\n"
+		}
+		if _, err := e.w.WriteString(separator); err != nil {
+			return err
+		}
+	}
+	e.count++
+	if _, err := e.w.Write(data); err != nil {
+		return err
+	}
+	if !e.array {
+		return e.w.WriteByte('\n')
+	}
+	return nil
+}
+
+func (e *jsonSongEncoder) end() error {
+	if !e.array {
+		return nil
+	}
+	closing := "]\n"
+	if e.count > 0 {
+		closing = "\n]\n"
+	}
+	_, err := e.w.WriteString(closing)
+	return err
+}
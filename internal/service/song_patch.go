@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch    = errors.New("invalid patch")
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// patchRetries bounds how often an unconditional patch is re-applied when a
+// concurrent write lands between reading the song and writing it back.
+const patchRetries = 3
+
+// PatchSong applies an RFC 7396 merge patch or an RFC 6902 JSON patch to a
+// song and writes back only the fields that changed. A non-zero ifVersion
+// makes the patch fail with repository.ErrVersionMismatch if the song has
+// changed since.
+func (s *SongService) PatchSong(ctx context.Context, id int, contentType string, patch []byte, ifVersion int) (*models.Song, error) {
+	for attempt := 0; ; attempt++ {
+		song, err := s.SongRepo.GetSongByID(ctx, strconv.Itoa(id))
+		if err != nil {
+			return nil, err
+		}
+		if ifVersion != 0 && song.Version != ifVersion {
+			return nil, repository.ErrVersionMismatch
+		}
+
+		patched, err := applySongPatch(*song, contentType, patch)
+		if err != nil {
+			return nil, err
+		}
+		normalizeSongRequest(&patched)
+		if err := s.ValidateSongRequest(patched); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+
+		changes := songChanges(*song, patched)
+		if changes.IsEmpty() {
+			return song, nil
+		}
+
+		err = s.SongRepo.PatchSong(ctx, id, changes, song.Version)
+		if errors.Is(err, repository.ErrVersionMismatch) && ifVersion == 0 && attempt < patchRetries {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to patch song: %w", err)
+		}
+		return s.SongRepo.GetSongByID(ctx, strconv.Itoa(id))
+	}
+}
+
+// patchDateLayout is how release dates appear in the patched document, so
+// a test op can compare them as plain dates.
+const patchDateLayout = "2006-01-02"
+
+// songDocument is the JSON representation patches are applied to. It has
+// the same members as SongRequest; an unset release date is null.
+func songDocument(song models.Song) map[string]any {
+	var releaseDate any
+	if song.ReleaseDate != nil && !song.ReleaseDate.IsZero() {
+		releaseDate = song.ReleaseDate.Format(patchDateLayout)
+	}
+	return map[string]any{
+		"group":        song.Group,
+		"song":         song.Song,
+		"release_date": releaseDate,
+		"lyrics":       song.Lyrics,
+		"link":         song.Link,
+	}
+}
+
+func applySongPatch(song models.Song, contentType string, patch []byte) (SongRequest, error) {
+	doc := songDocument(song)
+
+	var err error
+	switch contentType {
+	case MergePatchContentType:
+		err = applyMergePatch(doc, patch)
+	case JSONPatchContentType:
+		err = applyJSONPatch(doc, patch)
+	default:
+		return SongRequest{}, fmt.Errorf("%w: unsupported content type %q", ErrInvalidPatch, contentType)
+	}
+	if err != nil {
+		return SongRequest{}, err
+	}
+
+	for member, value := range doc {
+		if value == nil {
+			delete(doc, member)
+		}
+	}
+	if err := decodePatchedReleaseDate(doc); err != nil {
+		return SongRequest{}, err
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return SongRequest{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var result SongRequest
+	if err := decoder.Decode(&result); err != nil {
+		return SongRequest{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return result, nil
+}
+
+// decodePatchedReleaseDate turns a release date given as a date or in RFC
+// 3339 back into the form SongRequest decodes.
+func decodePatchedReleaseDate(doc map[string]any) error {
+	value, ok := doc["release_date"].(string)
+	if !ok {
+		return nil
+	}
+	releaseDate, err := time.Parse(patchDateLayout, value)
+	if err != nil {
+		if releaseDate, err = time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%w: release_date must be YYYY-MM-DD or RFC 3339", ErrInvalidPatch)
+		}
+	}
+	doc["release_date"] = releaseDate.Format(time.RFC3339)
+	return nil
+}
+
+// applyMergePatch implements RFC 7396 for the flat song document: members
+// set to null are removed and all others replace the current value.
+func applyMergePatch(doc map[string]any, patch []byte) error {
+	var members map[string]any
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+	}
+	for member, value := range members {
+		if value == nil {
+			delete(doc, member)
+			continue
+		}
+		doc[member] = value
+	}
+	return nil
+}
+
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch implements RFC 6902 for the flat song document, so every
+// path must name a top-level member such as /lyrics. Operations are applied
+// in order and the whole patch fails if any of them does.
+func applyJSONPatch(doc map[string]any, patch []byte) error {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return fmt.Errorf("%w: json patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	for i, operation := range operations {
+		if err := applyJSONPatchOperation(doc, operation); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func applyJSONPatchOperation(doc map[string]any, operation jsonPatchOperation) error {
+	member, err := patchMember(operation.Path)
+	if err != nil {
+		return err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, operation.Op)
+		}
+		var value any
+		if err := json.Unmarshal(*operation.Value, &value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		current, exists := doc[member]
+		switch operation.Op {
+		case "replace":
+			if !exists {
+				return fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, operation.Path)
+			}
+		case "test":
+			if !exists || !reflect.DeepEqual(current, value) {
+				return fmt.Errorf("%w: %s", ErrPatchTestFailed, operation.Path)
+			}
+			return nil
+		}
+		doc[member] = value
+	case "remove":
+		if _, exists := doc[member]; !exists {
+			return fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, operation.Path)
+		}
+		delete(doc, member)
+	case "move", "copy":
+		from, err := patchMember(operation.From)
+		if err != nil {
+			return err
+		}
+		value, exists := doc[from]
+		if !exists {
+			return fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, operation.From)
+		}
+		if operation.Op == "move" {
+			delete(doc, from)
+		}
+		doc[member] = value
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, operation.Op)
+	}
+	return nil
+}
+
+// patchMember resolves a JSON pointer naming a top-level document member.
+func patchMember(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, pointer)
+	}
+	member := strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:])
+	return member, nil
+}
+
+// songChanges lists the fields of patched that differ from song.
+func songChanges(song models.Song, patched SongRequest) repository.SongChanges {
+	var changes repository.SongChanges
+	if patched.Group != song.Group {
+		changes.Group = &patched.Group
+	}
+	if patched.Song != song.Song {
+		changes.Song = &patched.Song
+	}
+	if !sameReleaseDate(song.ReleaseDate, patched.ReleaseDate) {
+		changes.ReleaseDate = &patched.ReleaseDate
+	}
+	if patched.Lyrics != song.Lyrics {
+		changes.Lyrics = &patched.Lyrics
+	}
+	if patched.Link != song.Link {
+		changes.Link = &patched.Link
+	}
+	return changes
+}
+
+// sameReleaseDate compares at day precision, as release dates are stored
+// without a time of day.
+func sameReleaseDate(stored *time.Time, requested time.Time) bool {
+	if stored == nil || stored.IsZero() {
+		return requested.IsZero()
+	}
+	y1, m1, d1 := stored.Date()
+	y2, m2, d2 := requested.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"song-library/internal/models"
+	"song-library/internal/repository"
+	"testing"
+	"time"
+)
+
+func patchedSong() models.Song {
+	release := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+	return models.Song{
+		Group:       "Muse",
+		Song:        "Hysteria",
+		ReleaseDate: &release,
+		Lyrics:      "It's bugging me",
+		Link:        "https://example.com/hysteria",
+	}
+}
+
+func TestApplySongPatch(t *testing.T) {
+	release := time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC)
+	unchanged := SongRequest{Group: "Muse", Song: "Hysteria", ReleaseDate: release, Lyrics: "It's bugging me", Link: "https://example.com/hysteria"}
+	with := func(change func(*SongRequest)) SongRequest {
+		request := unchanged
+		change(&request)
+		return request
+	}
+
+	cases := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        SongRequest
+		wantErr     error
+	}{
+		{
+			name:        "merge patch replaces members",
+			contentType: MergePatchContentType,
+			patch:       `{"lyrics": "Thunder", "release_date": "2004-01-02"}`,
+			want: with(func(r *SongRequest) {
+				r.Lyrics = "Thunder"
+				r.ReleaseDate = time.Date(2004, time.January, 2, 0, 0, 0, 0, time.UTC)
+			}),
+		},
+		{
+			name:        "merge patch null removes members",
+			contentType: MergePatchContentType,
+			patch:       `{"lyrics": null, "release_date": null}`,
+			want:        with(func(r *SongRequest) { r.Lyrics, r.ReleaseDate = "", time.Time{} }),
+		},
+		{name: "merge patch must be an object", contentType: MergePatchContentType, patch: `["lyrics"]`, wantErr: ErrInvalidPatch},
+		{name: "merge patch with an unknown member", contentType: MergePatchContentType, patch: `{"genre": "rock"}`, wantErr: ErrInvalidPatch},
+		{name: "merge patch with a malformed date", contentType: MergePatchContentType, patch: `{"release_date": "01.12.2003"}`, wantErr: ErrInvalidPatch},
+		{
+			name:        "json patch test compares release dates as dates",
+			contentType: JSONPatchContentType,
+			patch:       `[{"op": "test", "path": "/release_date", "value": "2003-12-01"}, {"op": "replace", "path": "/lyrics", "value": "Thunder"}]`,
+			want:        with(func(r *SongRequest) { r.Lyrics = "Thunder" }),
+		},
+		{
+			name:        "json patch test failure",
+			contentType: JSONPatchContentType,
+			patch:       `[{"op": "test", "path": "/lyrics", "value": "Thunder"}, {"op": "replace", "path": "/lyrics", "value": "Lightning"}]`,
+			wantErr:     ErrPatchTestFailed,
+		},
+		{
+			name:        "json patch test of a removed member",
+			contentType: JSONPatchContentType,
+			patch:       `[{"op": "remove", "path": "/link"}, {"op": "test", "path": "/link", "value": "https://example.com/hysteria"}]`,
+			wantErr:     ErrPatchTestFailed,
+		},
+		{
+			name:        "json patch move",
+			contentType: JSONPatchContentType,
+			patch:       `[{"op": "move", "from": "/link", "path": "/lyrics"}]`,
+			want:        with(func(r *SongRequest) { r.Lyrics, r.Link = "https://example.com/hysteria", "" }),
+		},
+		{
+			name:        "json patch copy",
+			contentType: JSONPatchContentType,
+			patch:       `[{"op": "copy", "from": "/song", "path": "/lyrics"}]`,
+			want:        with(func(r *SongRequest) { r.Lyrics = "Hysteria" }),
+		},
+		{name: "json patch move from a missing member", contentType: JSONPatchContentType, patch: `[{"op": "remove", "path": "/link"}, {"op": "move", "from": "/link", "path": "/lyrics"}]`, wantErr: ErrInvalidPatch},
+		{name: "json patch adding an unknown member", contentType: JSONPatchContentType, patch: `[{"op": "add", "path": "/genre", "value": "rock"}]`, wantErr: ErrInvalidPatch},
+		{name: "json patch replacing a missing member", contentType: JSONPatchContentType, patch: `[{"op": "remove", "path": "/link"}, {"op": "replace", "path": "/link", "value": "x"}]`, wantErr: ErrInvalidPatch},
+		{name: "json patch with a nested path", contentType: JSONPatchContentType, patch: `[{"op": "replace", "path": "/song/title", "value": "x"}]`, wantErr: ErrInvalidPatch},
+		{name: "json patch with an unknown op", contentType: JSONPatchContentType, patch: `[{"op": "merge", "path": "/lyrics", "value": "x"}]`, wantErr: ErrInvalidPatch},
+		{name: "json patch without a value", contentType: JSONPatchContentType, patch: `[{"op": "add", "path": "/lyrics"}]`, wantErr: ErrInvalidPatch},
+		{name: "unsupported content type", contentType: "application/json", patch: `{}`, wantErr: ErrInvalidPatch},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := applySongPatch(patchedSong(), tc.contentType, []byte(tc.patch))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.ReleaseDate.Equal(tc.want.ReleaseDate) {
+				t.Errorf("release date = %v, want %v", got.ReleaseDate, tc.want.ReleaseDate)
+			}
+			got.ReleaseDate, tc.want.ReleaseDate = time.Time{}, time.Time{}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSongChanges(t *testing.T) {
+	song := patchedSong()
+	request := SongRequest{Group: song.Group, Song: song.Song, ReleaseDate: *song.ReleaseDate, Lyrics: song.Lyrics, Link: song.Link}
+
+	if changes := songChanges(song, request); !changes.IsEmpty() {
+		t.Errorf("unchanged song gave %+v", changes)
+	}
+
+	laterSameDay := request
+	laterSameDay.ReleaseDate = song.ReleaseDate.Add(15 * time.Hour)
+	if changes := songChanges(song, laterSameDay); !changes.IsEmpty() {
+		t.Errorf("release date on the same day gave %+v", changes)
+	}
+
+	edited := request
+	edited.Lyrics = "Thunder"
+	edited.ReleaseDate = time.Time{}
+	changes := songChanges(song, edited)
+	if changes.Lyrics == nil || *changes.Lyrics != "Thunder" || changes.ReleaseDate == nil || !changes.ReleaseDate.IsZero() {
+		t.Errorf("got %+v, want the lyrics and a cleared release date", changes)
+	}
+	if changes.Group != nil || changes.Song != nil || changes.Link != nil {
+		t.Errorf("got %+v, want only the lyrics and release date", changes)
+	}
+}
+
+func TestPatchSongSkipsUnchangedWrite(t *testing.T) {
+	ctx := context.Background()
+	s := NewSongService(repository.NewMemorySongRepository(), Config{CursorSecret: "test"})
+	song := patchedSong()
+	result, err := s.AddSong(ctx, SongRequest{Group: song.Group, Song: song.Song, ReleaseDate: *song.ReleaseDate, Lyrics: song.Lyrics, Link: song.Link}, ConflictError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := result.Song.ID
+
+	patched, err := s.PatchSong(ctx, id, JSONPatchContentType, []byte(`[{"op": "replace", "path": "/lyrics", "value": "It's bugging me"}]`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Version != result.Song.Version {
+		t.Errorf("version = %d, want %d", patched.Version, result.Song.Version)
+	}
+	revisions, err := s.GetSongRevisions(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Errorf("got %d revisions, want 1", len(revisions))
+	}
+
+	if _, err := s.PatchSong(ctx, id, JSONPatchContentType, []byte(`[{"op": "test", "path": "/lyrics", "value": "Thunder"}]`), 0); !errors.Is(err, ErrPatchTestFailed) {
+		t.Errorf("got %v, want ErrPatchTestFailed", err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"song-library/internal/models"
+)
+
+// SongRevisionDetail is a revision together with the line-level lyrics diff
+// against the revision before it. The first revision is diffed against empty
+// lyrics. When the changed lines are too many to diff, LyricsDiff is empty
+// and LyricsDiffTooLarge is set.
+type SongRevisionDetail struct {
+	models.SongRevision
+	PreviousRevision   int        `json:"previous_revision,omitempty"`
+	LyricsDiff         []DiffLine `json:"lyrics_diff"`
+	LyricsDiffTooLarge bool       `json:"lyrics_diff_too_large,omitempty"`
+}
+
+func (s *SongService) GetSongRevisions(ctx context.Context, songID int) ([]models.SongRevision, error) {
+	return s.SongRepo.GetSongRevisions(ctx, songID)
+}
+
+func (s *SongService) GetSongRevision(ctx context.Context, songID, revision int) (*SongRevisionDetail, error) {
+	current, err := s.SongRepo.GetSongRevision(ctx, songID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &SongRevisionDetail{SongRevision: *current}
+	previousLyrics := ""
+	if revision > 1 {
+		previous, err := s.SongRepo.GetSongRevision(ctx, songID, revision-1)
+		if err != nil {
+			return nil, fmt.Errorf("could not load revision %d: %w", revision-1, err)
+		}
+		detail.PreviousRevision = previous.Revision
+		previousLyrics = previous.Song.Lyrics
+	}
+	diff, ok := diffLyrics(previousLyrics, current.Song.Lyrics)
+	detail.LyricsDiff, detail.LyricsDiffTooLarge = diff, !ok
+	return detail, nil
+}
+
+// RollbackSong restores a song to the state captured in one of its revisions.
+func (s *SongService) RollbackSong(ctx context.Context, songID, revision int) error {
+	return s.SongRepo.RollbackSong(ctx, songID, revision)
+}
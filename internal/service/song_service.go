@@ -1,10 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"song-library/internal/models"
 	"song-library/internal/repository"
+	"song-library/pkg/resilience"
 	"strconv"
 	"strings"
 	"time"
@@ -18,18 +22,87 @@ type SongRequest struct {
 	Link        string    `json:"link"`
 }
 
+type MusicInfoConfig struct {
+	URL        string
+	Resilience resilience.Config
+}
+
+type Config struct {
+	MusicInfo    MusicInfoConfig
+	CursorSecret string
+	// FuzzyThreshold is the default minimum similarity for fuzzy matches and
+	// "did you mean" suggestions.
+	FuzzyThreshold float64
+	// BulkMaxItems caps the number of songs accepted by one bulk request.
+	BulkMaxItems int
+}
+
+const (
+	defaultFuzzyThreshold = 0.3
+	defaultBulkMaxItems   = 50000
+	// defaultMusicInfoTimeout bounds music info requests whose attempts
+	// have no timeout of their own.
+	defaultMusicInfoTimeout = 10 * time.Second
+)
+
 type SongService struct {
-	SongRepo   *repository.SongRepository
-	HTTPClient *http.Client
+	SongRepo       repository.SongStore
+	HTTPClient     *http.Client
+	MusicInfoURL   string
+	FuzzyThreshold float64
+	BulkMaxItems   int
+	transport      *resilience.Transport
+	cursors        cursorCodec
+}
+
+type SongPage struct {
+	Items      []models.Song `json:"items"`
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	Total      int           `json:"total"`
+	TotalPages int           `json:"total_pages"`
+	DidYouMean *Suggestion   `json:"did_you_mean,omitempty"`
+}
+
+type Suggestion struct {
+	Group string `json:"group,omitempty"`
+	Song  string `json:"song,omitempty"`
+}
+
+type SongCursorPage struct {
+	Items      []models.Song `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
 }
 
-func NewSongService(songRepo *repository.SongRepository) *SongService {
+func NewSongService(songRepo repository.SongStore, cfg Config) *SongService {
+	if cfg.FuzzyThreshold <= 0 || cfg.FuzzyThreshold > 1 {
+		cfg.FuzzyThreshold = defaultFuzzyThreshold
+	}
+	if cfg.BulkMaxItems < 1 {
+		cfg.BulkMaxItems = defaultBulkMaxItems
+	}
+	transport := resilience.NewTransport(http.DefaultTransport, cfg.MusicInfo.Resilience)
+	// The client must not cut off the retries the transport is still making.
+	timeout := cfg.MusicInfo.Resilience.Budget()
+	if timeout == 0 {
+		timeout = defaultMusicInfoTimeout
+	}
 	return &SongService{
-		SongRepo:   songRepo,
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		SongRepo:       songRepo,
+		HTTPClient:     &http.Client{Timeout: timeout, Transport: transport},
+		MusicInfoURL:   cfg.MusicInfo.URL,
+		FuzzyThreshold: cfg.FuzzyThreshold,
+		BulkMaxItems:   cfg.BulkMaxItems,
+		transport:      transport,
+		cursors:        cursorCodec{secret: cursorSecret(cfg.CursorSecret)},
 	}
 }
 
+func (s *SongService) BreakerStatus() []resilience.BreakerStatus {
+	return s.transport.Breakers()
+}
+
 func (s *SongService) ValidateSongRequest(songRequest SongRequest) error {
 	if songRequest.Song == "" {
 		return fmt.Errorf("song name cannot be empty")
@@ -37,12 +110,177 @@ func (s *SongService) ValidateSongRequest(songRequest SongRequest) error {
 	return nil
 }
 
-func (s *SongService) GetSongs(group, song string, page, limit int) ([]models.Song, error) {
-	return s.SongRepo.GetSongs(group, song, page, limit)
+func (s *SongService) GetSongs(ctx context.Context, filter repository.SongFilter, sort repository.SongSort, page, limit int) (*SongPage, error) {
+	songs, err := s.SongRepo.GetSongs(ctx, filter, sort, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.SongRepo.CountSongs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SongPage{
+		Items:      songs,
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}
+	if total == 0 && suggestible(filter) {
+		result.DidYouMean = s.suggest(ctx, filter)
+	}
+	return result, nil
+}
+
+// GetSongList is GetSongs without the count, for callers of the bare array:
+// Total and TotalPages are left zero and a suggestion is only made when the
+// first page is empty.
+func (s *SongService) GetSongList(ctx context.Context, filter repository.SongFilter, sort repository.SongSort, page, limit int) (*SongPage, error) {
+	songs, err := s.SongRepo.GetSongs(ctx, filter, sort, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SongPage{Items: songs, Page: page, Limit: limit}
+	if page == 1 && len(songs) == 0 && suggestible(filter) {
+		result.DidYouMean = s.suggest(ctx, filter)
+	}
+	return result, nil
+}
+
+// suggestible reports whether a filter that matched nothing has names worth
+// suggesting alternatives for.
+func suggestible(filter repository.SongFilter) bool {
+	return !filter.Trashed && (filter.Group != "" || filter.Song != "")
+}
+
+// suggest proposes existing names close to a filter that matched nothing.
+// It is best effort: stores without fuzzy matching and lookup errors simply
+// yield no suggestion.
+func (s *SongService) suggest(ctx context.Context, filter repository.SongFilter) *Suggestion {
+	matcher, ok := s.SongRepo.(repository.FuzzyMatcher)
+	if !ok {
+		return nil
+	}
+
+	suggested, err := matcher.SuggestSongFilter(ctx, filter, s.FuzzyThreshold)
+	if err != nil {
+		log.Printf("Error fetching suggestions: %v", err)
+		return nil
+	}
+	if suggested == nil {
+		return nil
+	}
+	return &Suggestion{Group: suggested.Group, Song: suggested.Song}
+}
+
+var ErrFuzzyUnsupported = errors.New("fuzzy matching is not supported by the song store")
+
+type ScoredSongPage struct {
+	Items []models.ScoredSong `json:"items"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+}
+
+// FuzzySongs matches group and song names by trigram similarity, best match
+// first. A zero threshold falls back to the configured default.
+func (s *SongService) FuzzySongs(ctx context.Context, query repository.FuzzyQuery) (*ScoredSongPage, error) {
+	matcher, ok := s.SongRepo.(repository.FuzzyMatcher)
+	if !ok {
+		return nil, ErrFuzzyUnsupported
+	}
+	if query.Threshold == 0 {
+		query.Threshold = s.FuzzyThreshold
+	}
+
+	songs, err := matcher.FuzzySongs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if songs == nil {
+		songs = []models.ScoredSong{}
+	}
+	return &ScoredSongPage{Items: songs, Page: query.Page, Limit: query.Limit}, nil
+}
+
+// GetSongsByCursor walks the library in sort order. An empty cursor starts
+// at the beginning; otherwise it must be a next_cursor or prev_cursor token
+// returned by an earlier call with the same sort and filter.
+func (s *SongService) GetSongsByCursor(ctx context.Context, filter repository.SongFilter, sort repository.SongSort, cursor string, limit int) (*SongCursorPage, error) {
+	var keyset repository.Keyset
+	if cursor != "" {
+		var err error
+		if keyset, err = s.cursors.decode(sort, filter, cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := s.SongRepo.GetSongsByKeyset(ctx, filter, sort, keyset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SongCursorPage{Items: page.Songs}
+	if result.Items == nil {
+		result.Items = []models.Song{}
+	}
+
+	if len(page.Songs) == 0 {
+		// Nothing left in the direction of travel; offer a way back that
+		// includes the row the cursor pointed at.
+		if cursor != "" {
+			back := repository.Keyset{Values: keyset.Values, Backward: !keyset.Backward, Inclusive: true}
+			if keyset.Backward {
+				result.NextCursor = s.cursors.encode(sort, filter, back)
+			} else {
+				result.PrevCursor = s.cursors.encode(sort, filter, back)
+			}
+		}
+		return result, nil
+	}
+
+	hasNext, hasPrev := page.HasMore, cursor != ""
+	if keyset.Backward {
+		hasNext, hasPrev = true, page.HasMore
+	}
+	if hasNext {
+		last := sort.Values(page.Songs[len(page.Songs)-1])
+		result.NextCursor = s.cursors.encode(sort, filter, repository.Keyset{Values: last})
+	}
+	if hasPrev {
+		first := sort.Values(page.Songs[0])
+		result.PrevCursor = s.cursors.encode(sort, filter, repository.Keyset{Values: first, Backward: true})
+	}
+	return result, nil
+}
+
+var ErrSearchUnsupported = errors.New("full-text search is not supported by the song store")
+
+type SongSearchPage struct {
+	Items []models.SongSearchResult `json:"items"`
+	Page  int                       `json:"page"`
+	Limit int                       `json:"limit"`
+}
+
+func (s *SongService) SearchSongs(ctx context.Context, query repository.SearchQuery) (*SongSearchPage, error) {
+	searcher, ok := s.SongRepo.(repository.SongSearcher)
+	if !ok {
+		return nil, ErrSearchUnsupported
+	}
+
+	results, err := searcher.SearchSongs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []models.SongSearchResult{}
+	}
+	return &SongSearchPage{Items: results, Page: query.Page, Limit: query.Limit}, nil
 }
 
-func (s *SongService) GetSongByID(id string) (*models.Song, error) {
-	song, err := s.SongRepo.GetSongByID(id)
+func (s *SongService) GetSongByID(ctx context.Context, id string) (*models.Song, error) {
+	song, err := s.SongRepo.GetSongByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -50,8 +288,8 @@ func (s *SongService) GetSongByID(id string) (*models.Song, error) {
 	return song, nil
 }
 
-func (s *SongService) GetSongLyricsWithRange(songID, start, end int) (string, error) {
-	song, err := s.SongRepo.GetSongByID(strconv.Itoa(songID))
+func (s *SongService) GetSongLyricsWithRange(ctx context.Context, songID, start, end int) (string, error) {
+	song, err := s.SongRepo.GetSongByID(ctx, strconv.Itoa(songID))
 	if err != nil {
 		return "", fmt.Errorf("could not retrieve song with ID %d: %w", songID, err)
 	}
@@ -78,9 +316,31 @@ func (s *SongService) GetSongLyricsWithRange(songID, start, end int) (string, er
 	return strings.Join(paginatedVerses, "\n\n"), nil
 }
 
-func (s *SongService) AddSong(songRequest SongRequest) error {
+// AddSong saves a new song. A song with the same group and title as a live
+// one is handled as onConflict says.
+func (s *SongService) AddSong(ctx context.Context, songRequest SongRequest, onConflict ConflictPolicy) (*AddSongResult, error) {
+	normalizeSongRequest(&songRequest)
 	if err := s.ValidateSongRequest(songRequest); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+
+	if s.MusicInfoURL != "" && needsEnrichment(songRequest) {
+		// A song that is going to be rejected or ignored as a duplicate is
+		// not worth a call to the music-info API.
+		if onConflict != ConflictUpdate {
+			existing, err := s.SongRepo.FindDuplicateSongs(ctx, []models.Song{{Group: songRequest.Group, Song: songRequest.Song}})
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up duplicates: %w", err)
+			}
+			if existing[0] != 0 {
+				return s.resolveDuplicate(ctx, &repository.DuplicateSongError{ExistingID: existing[0]}, onConflict)
+			}
+		}
+		if err := s.enrichSongRequest(ctx, &songRequest); errors.Is(err, errUpstreamUnavailable) || errors.Is(err, errNoSongDetail) {
+			log.Printf("Saving song %q without enrichment: %v", songRequest.Song, err)
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrEnrichment, err)
+		}
 	}
 
 	song := models.Song{
@@ -91,15 +351,44 @@ func (s *SongService) AddSong(songRequest SongRequest) error {
 		Link:        songRequest.Link,
 	}
 
-	if err := s.SongRepo.AddSong(song); err != nil {
-		return fmt.Errorf("failed to save song: %w", err)
+	if onConflict == ConflictUpdate {
+		stored, created, err := s.SongRepo.UpsertSong(ctx, song)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save song: %w", err)
+		}
+		if created {
+			return &AddSongResult{Song: stored, Status: SongCreated}, nil
+		}
+		return &AddSongResult{Song: stored, Status: SongUpdated}, nil
 	}
-	return nil
+
+	added, err := s.SongRepo.AddSong(ctx, song)
+	if err == nil {
+		return &AddSongResult{Song: added, Status: SongCreated}, nil
+	}
+	return s.resolveDuplicate(ctx, err, onConflict)
+}
+
+// resolveDuplicate answers an add that failed with err: a duplicate is
+// returned as SongIgnored under ConflictIgnore, anything else fails.
+func (s *SongService) resolveDuplicate(ctx context.Context, err error, onConflict ConflictPolicy) (*AddSongResult, error) {
+	var duplicate *repository.DuplicateSongError
+	if !errors.As(err, &duplicate) || onConflict == ConflictError {
+		return nil, fmt.Errorf("failed to save song: %w", err)
+	}
+	existing, err := s.SongRepo.GetSongByID(ctx, strconv.Itoa(duplicate.ExistingID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch song %d: %w", duplicate.ExistingID, err)
+	}
+	return &AddSongResult{Song: existing, Status: SongIgnored}, nil
 }
 
-func (s *SongService) UpdateSong(id int, songRequest SongRequest) error {
+// UpdateSong replaces a song. A non-zero ifVersion makes the update fail
+// with repository.ErrVersionMismatch if the song has changed since.
+func (s *SongService) UpdateSong(ctx context.Context, id int, songRequest SongRequest, ifVersion int) error {
+	normalizeSongRequest(&songRequest)
 	if err := s.ValidateSongRequest(songRequest); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return fmt.Errorf("%w: %v", ErrValidation, err)
 	}
 
 	song := models.Song{
@@ -110,12 +399,28 @@ func (s *SongService) UpdateSong(id int, songRequest SongRequest) error {
 		Link:        songRequest.Link,
 	}
 
-	if err := s.SongRepo.UpdateSong(id, song); err != nil {
+	if err := s.SongRepo.UpdateSong(ctx, id, song, ifVersion); err != nil {
 		return fmt.Errorf("failed to update song: %w", err)
 	}
 	return nil
 }
 
-func (s *SongService) DeleteSong(id int) error {
-	return s.SongRepo.DeleteSong(id)
+func (s *SongService) DeleteSong(ctx context.Context, id int, ifVersion int) error {
+	return s.SongRepo.DeleteSong(ctx, id, ifVersion)
+}
+
+// GetTrash lists soft-deleted songs with the same filtering, sorting and
+// paging as GetSongs.
+func (s *SongService) GetTrash(ctx context.Context, filter repository.SongFilter, sort repository.SongSort, page, limit int) (*SongPage, error) {
+	filter.Trashed = true
+	return s.GetSongs(ctx, filter, sort, page, limit)
+}
+
+func (s *SongService) RestoreSong(ctx context.Context, id int) error {
+	return s.SongRepo.RestoreSong(ctx, id)
+}
+
+// PurgeSong permanently deletes a song that is already in the trash.
+func (s *SongService) PurgeSong(ctx context.Context, id int) error {
+	return s.SongRepo.PurgeSong(ctx, id)
 }
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type TrashPurgerConfig struct {
+	// Retention is how long a song stays in the trash before it is purged.
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// TrashPurger periodically hard-deletes songs that have been in the trash for
+// longer than the configured retention window.
+type TrashPurger struct {
+	songService *SongService
+	cfg         TrashPurgerConfig
+}
+
+func NewTrashPurger(songService *SongService, cfg TrashPurgerConfig) *TrashPurger {
+	return &TrashPurger{songService: songService, cfg: cfg}
+}
+
+// Start runs the purger until ctx is cancelled. It does nothing unless both
+// the retention window and the interval are set.
+func (p *TrashPurger) Start(ctx context.Context) {
+	if p.cfg.Retention <= 0 || p.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.purgeOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purgeOnce(ctx context.Context) {
+	cutoff := time.Now().Add(-p.cfg.Retention)
+	purged, err := p.songService.SongRepo.PurgeDeletedSongs(ctx, cutoff)
+	if err != nil {
+		log.Printf("Trash purge failed: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d songs deleted before %s", purged, cutoff.Format(time.RFC3339))
+	}
+}
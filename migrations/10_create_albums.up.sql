@@ -0,0 +1,27 @@
+-- Albums released by a group. A compilation is filed under the group it is
+-- credited to, while its tracks may be songs of any group.
+CREATE TABLE IF NOT EXISTS albums (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    release_date DATE,
+    type VARCHAR(16) NOT NULL DEFAULT 'lp' CHECK (type IN ('lp', 'ep', 'single', 'compilation')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_albums_group_id ON albums(group_id);
+CREATE INDEX IF NOT EXISTS idx_albums_title ON albums(title);
+
+-- The songs on an album. A song appears at most once per album and every
+-- disc and track number holds one song.
+CREATE TABLE IF NOT EXISTS album_tracks (
+    album_id INT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    disc INT NOT NULL DEFAULT 1 CHECK (disc > 0),
+    track INT NOT NULL CHECK (track > 0),
+    PRIMARY KEY (album_id, song_id),
+    UNIQUE (album_id, disc, track)
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_tracks_song_id ON album_tracks(song_id);
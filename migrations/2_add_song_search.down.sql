@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_songs_search_russian;
+DROP INDEX IF EXISTS idx_songs_search_english;
+DROP INDEX IF EXISTS idx_songs_search_vector;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,20 @@
+-- Lyrics may store line breaks as a literal backslash-n sequence, so both
+-- the indexed document and the verse splitting below normalize them first.
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(song, '')), 'A') ||
+        setweight(to_tsvector('simple', replace(COALESCE(lyrics, ''), '\n', ' ')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+
+-- Expression indexes for the language configurations selectable per request.
+CREATE INDEX IF NOT EXISTS idx_songs_search_english ON songs USING GIN ((
+    setweight(to_tsvector('english', COALESCE(song, '')), 'A') ||
+    setweight(to_tsvector('english', replace(COALESCE(lyrics, ''), '\n', ' ')), 'B')
+));
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_russian ON songs USING GIN ((
+    setweight(to_tsvector('russian', COALESCE(song, '')), 'A') ||
+    setweight(to_tsvector('russian', replace(COALESCE(lyrics, ''), '\n', ' ')), 'B')
+));
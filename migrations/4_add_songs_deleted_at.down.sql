@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_songs_deleted_at;
+
+DELETE FROM songs WHERE deleted_at IS NOT NULL;
+ALTER TABLE songs DROP COLUMN IF EXISTS deleted_at;
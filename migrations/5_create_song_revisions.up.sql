@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS song_revisions (
+    id SERIAL PRIMARY KEY,
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    song VARCHAR(255) NOT NULL,
+    release_date DATE,
+    lyrics TEXT NULL,
+    link VARCHAR(255),
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (song_id, revision)
+);
+
+-- Existing songs start their history with a snapshot of their current state.
+INSERT INTO song_revisions (song_id, revision, group_name, song, release_date, lyrics, link, changed_fields, created_at)
+SELECT s.id, 1, g.name, s.song, s.release_date, s.lyrics, s.link,
+       ARRAY['group', 'song', 'release_date', 'lyrics', 'link'],
+       COALESCE(s.updated_at, s.created_at, CURRENT_TIMESTAMP)
+FROM songs s
+JOIN groups g ON s.group_id = g.id
+ON CONFLICT (song_id, revision) DO NOTHING;
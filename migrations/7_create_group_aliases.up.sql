@@ -0,0 +1,10 @@
+-- Names of groups merged into another group. Songs written under an alias are
+-- filed under the group it points to.
+CREATE TABLE IF NOT EXISTS group_aliases (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    alias VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_aliases_group_id ON group_aliases(group_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_group_aliases_alias_key;
+DROP INDEX IF EXISTS idx_groups_name_key;
+
+ALTER TABLE group_aliases DROP COLUMN IF EXISTS alias_key;
+ALTER TABLE groups DROP COLUMN IF EXISTS name_key;
@@ -0,0 +1,8 @@
+-- Case-folded, normalized forms of group names and aliases used for matching.
+-- Keys are computed by the application; run cmd/backfill-name-keys to fill
+-- them for existing rows.
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS name_key TEXT NULL;
+ALTER TABLE group_aliases ADD COLUMN IF NOT EXISTS alias_key TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_groups_name_key ON groups(name_key);
+CREATE INDEX IF NOT EXISTS idx_group_aliases_alias_key ON group_aliases(alias_key);
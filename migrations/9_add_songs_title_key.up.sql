@@ -0,0 +1,7 @@
+-- Case-folded, normalized song title used to keep a group from holding two
+-- live songs with the same title. Keys are computed by the application; run
+-- cmd/backfill-name-keys to fill them for existing rows. Rows without a key
+-- are not covered by the index.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS title_key TEXT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_group_title_key ON songs(group_id, title_key) WHERE deleted_at IS NULL;
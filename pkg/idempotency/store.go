@@ -0,0 +1,112 @@
+// Package idempotency records the responses to requests that carry an
+// idempotency key, so a retried request is answered from the record instead
+// of being executed a second time.
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInProgress = errors.New("a request with this key is still in progress")
+	ErrMismatch   = errors.New("key was already used for a different request")
+)
+
+// Response is a recorded response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	fingerprint string
+	response    *Response
+	expiresAt   time.Time
+}
+
+// Store keeps recorded responses for a fixed window after they complete. It
+// lives in process memory, so every instance of the service has its own.
+type Store struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*entry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// defaultWindow is how long responses are kept when no window is configured.
+const defaultWindow = 24 * time.Hour
+
+// NewStore returns a store keeping responses for window, or for 24 hours when
+// window is not positive.
+func NewStore(window time.Duration) *Store {
+	if window <= 0 {
+		window = defaultWindow
+	}
+	return &Store{window: window, entries: make(map[string]*entry), now: time.Now}
+}
+
+// Begin claims key for a request identified by fingerprint. If the key has
+// already completed for the same fingerprint its response is returned. A key
+// still being processed fails with ErrInProgress and a key used with another
+// fingerprint with ErrMismatch. Otherwise the key is claimed and the caller
+// must Complete or Release it.
+func (s *Store) Begin(key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && (e.response == nil || now.Before(e.expiresAt)) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, ErrMismatch
+		case e.response == nil:
+			return nil, ErrInProgress
+		default:
+			return e.response, nil
+		}
+	}
+	s.entries[key] = &entry{fingerprint: fingerprint}
+	return nil, nil
+}
+
+// Complete records the response to a claimed key for the store's window.
+func (s *Store) Complete(key string, response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		e.response = &response
+		e.expiresAt = s.now().Add(s.window)
+	}
+}
+
+// Release gives up a claimed key without recording a response, so the
+// request can be retried.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		delete(s.entries, key)
+	}
+}
+
+// sweep drops expired responses, at most once a minute. It must be called
+// with the lock held.
+func (s *Store) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, e := range s.entries {
+		if e.response != nil && !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.nextSweep = now.Add(time.Minute)
+}
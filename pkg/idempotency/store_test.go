@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreWindow(t *testing.T) {
+	cases := map[string]struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		"configured": {window: time.Hour, want: time.Hour},
+		"zero":       {window: 0, want: 24 * time.Hour},
+		"negative":   {window: -time.Minute, want: 24 * time.Hour},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+			store := NewStore(tc.window)
+			store.now = func() time.Time { return now }
+
+			if _, err := store.Begin("key", "POST /songs"); err != nil {
+				t.Fatal(err)
+			}
+			store.Complete("key", Response{Status: 201})
+
+			now = now.Add(tc.want - time.Second)
+			if recorded, err := store.Begin("key", "POST /songs"); err != nil || recorded == nil || recorded.Status != 201 {
+				t.Fatalf("within the window: got %+v, %v, want the recorded response", recorded, err)
+			}
+			now = now.Add(2 * time.Second)
+			if recorded, err := store.Begin("key", "POST /songs"); err != nil || recorded != nil {
+				t.Fatalf("after the window: got %+v, %v, want the key to be free", recorded, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Package normalize canonicalizes group names and song titles so that
+// spellings differing only in Unicode form, spacing, quote style or case can
+// be matched.
+package normalize
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+)
+
+// quoteFolder replaces typographic quotes and primes with their ASCII
+// counterparts.
+var quoteFolder = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'", "´", "'", "`", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`, "«", `"`, "»", `"`,
+)
+
+// Name returns the canonical spelling of a name: NFC normalized, with smart
+// quotes folded to ASCII and runs of whitespace collapsed to a single space.
+func Name(name string) string {
+	name = norm.NFC.String(name)
+	name = quoteFolder.Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Key returns the matching key of a name: its canonical spelling, case
+// folded. Names with the same key are considered the same.
+func Key(name string) string {
+	return norm.NFC.String(cases.Fold().String(Name(name)))
+}
@@ -0,0 +1,57 @@
+package normalize
+
+import "testing"
+
+func TestName(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "already canonical", in: "Muse", want: "Muse"},
+		{name: "NFD is composed", in: "Beyonce\u0301", want: "Beyonc\u00e9"},
+		{name: "NFC is kept", in: "Beyonc\u00e9", want: "Beyonc\u00e9"},
+		{name: "smart single quotes", in: "Don’t Stop Me Now", want: "Don't Stop Me Now"},
+		{name: "smart double quotes", in: "“Heroes”", want: `"Heroes"`},
+		{name: "primes", in: "12′ and 7″", want: `12' and 7"`},
+		{name: "guillemets", in: "«La Vie»", want: `"La Vie"`},
+		{name: "whitespace runs", in: "  Arctic \t Monkeys\n", want: "Arctic Monkeys"},
+		{name: "no-break space", in: "The\u00a0Killers", want: "The Killers"},
+		{name: "case is kept", in: "STRASSE", want: "STRASSE"},
+		{name: "empty", in: " \t ", want: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Name(tc.in); got != tc.want {
+				t.Errorf("Name(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "case", a: "Imagine Dragons", b: "IMAGINE DRAGONS", same: true},
+		{name: "NFC and NFD", a: "Beyonc\u00e9", b: "BEYONCE\u0301", same: true},
+		{name: "sharp s folds to ss", a: "Straße", b: "STRASSE", same: true},
+		{name: "final sigma", a: "ΟΔΟΣ", b: "οδος", same: true},
+		{name: "smart quotes", a: "Don’t Stop Me Now", b: "don't stop me now", same: true},
+		{name: "whitespace runs", a: "Arctic   Monkeys", b: " arctic monkeys ", same: true},
+		{name: "accents are significant", a: "Beyonc\u00e9", b: "Beyonce", same: false},
+		{name: "punctuation is significant", a: "R U Mine?", b: "R U Mine", same: false},
+		{name: "inner spaces are significant", a: "Muse", b: "Mu se", same: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if same := Key(tc.a) == Key(tc.b); same != tc.same {
+				t.Errorf("Key(%q) = %q, Key(%q) = %q, same = %v, want %v", tc.a, Key(tc.a), tc.b, Key(tc.b), same, tc.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type Config struct {
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	MaxBackoff       time.Duration
+	AttemptTimeout   time.Duration
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// Budget returns the longest a retried request can take: every attempt
+// running into AttemptTimeout plus the longest backoff between them. It is
+// zero when attempts are not bounded.
+func (c Config) Budget() time.Duration {
+	if c.AttemptTimeout <= 0 {
+		return 0
+	}
+	budget := time.Duration(c.MaxRetries+1) * c.AttemptTimeout
+	for attempt := 0; attempt < c.MaxRetries; attempt++ {
+		budget += c.maxBackoff(attempt)
+	}
+	return budget
+}
+
+// Transport is an http.RoundTripper that retries idempotent requests on
+// timeouts and 5xx responses and guards every host with a circuit breaker.
+// Requests that the caller cancels do not count against the breaker.
+type Transport struct {
+	Base http.RoundTripper
+	cfg  Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewTransport(base http.RoundTripper, cfg Config) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, cfg: cfg, breakers: make(map[string]*Breaker)}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.breaker(req.URL.Host)
+	retries := t.cfg.MaxRetries
+	if !isIdempotent(req) {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !breaker.Allow() {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				breaker.RecordCanceled()
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			breaker.RecordSuccess()
+			return resp, nil
+		}
+		if err != nil && req.Context().Err() != nil {
+			breaker.RecordCanceled()
+			return nil, err
+		}
+		breaker.RecordFailure()
+
+		if attempt >= retries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(t.backoff(attempt)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func (t *Transport) Breakers() []BreakerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]BreakerStatus, 0, len(t.breakers))
+	for _, breaker := range t.breakers {
+		statuses = append(statuses, breaker.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Host < statuses[j].Host })
+	return statuses
+}
+
+func (t *Transport) breaker(host string) *Breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	breaker, ok := t.breakers[host]
+	if !ok {
+		breaker = NewBreaker(host, t.cfg.FailureThreshold, t.cfg.OpenTimeout)
+		t.breakers[host] = breaker
+	}
+	return breaker
+}
+
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.cfg.AttemptTimeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.cfg.AttemptTimeout)
+	resp, err := t.Base.RoundTrip(req.Clone(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns an exponentially growing delay with full jitter.
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.cfg.maxBackoff(attempt)
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+func (c Config) maxBackoff(attempt int) time.Duration {
+	delay := c.BaseBackoff << attempt
+	if delay <= 0 || (c.MaxBackoff > 0 && delay > c.MaxBackoff) {
+		delay = c.MaxBackoff
+	}
+	return max(delay, 0)
+}
+
+// isIdempotent reports whether req may be sent again: its method is
+// idempotent, or the caller opted in with an Idempotency-Key header as
+// net/http does. A body must be replayable through GetBody.
+func isIdempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		_, key := req.Header["Idempotency-Key"]
+		_, xKey := req.Header["X-Idempotency-Key"]
+		return key || xKey
+	}
+}
+
+// cancelOnClose releases the per-attempt context once the body is consumed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,122 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportRetries(t *testing.T) {
+	cases := []struct {
+		name      string
+		method    string
+		body      string
+		header    string
+		wantCalls int32
+	}{
+		{name: "GET is retried", method: http.MethodGet, wantCalls: 3},
+		{name: "PUT is retried", method: http.MethodPut, body: "song", wantCalls: 3},
+		{name: "DELETE is retried", method: http.MethodDelete, wantCalls: 3},
+		{name: "POST without a body is not retried", method: http.MethodPost, wantCalls: 1},
+		{name: "PATCH without a body is not retried", method: http.MethodPatch, wantCalls: 1},
+		{name: "POST with a body is not retried", method: http.MethodPost, body: "song", wantCalls: 1},
+		{name: "POST with an Idempotency-Key is retried", method: http.MethodPost, body: "song", header: "Idempotency-Key", wantCalls: 3},
+		{name: "POST with an X-Idempotency-Key is retried", method: http.MethodPost, header: "X-Idempotency-Key", wantCalls: 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if body, _ := io.ReadAll(r.Body); string(body) != tc.body {
+					t.Errorf("attempt %d got body %q, want %q", calls.Load(), body, tc.body)
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			transport := NewTransport(nil, Config{MaxRetries: 2, FailureThreshold: 10, OpenTimeout: time.Minute})
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req, err := http.NewRequest(tc.method, server.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.header != "" {
+				req.Header.Set(tc.header, "key")
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("server called %d times, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTransportIgnoresCallerCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	transport := NewTransport(nil, Config{MaxRetries: 2, FailureThreshold: 1, OpenTimeout: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	status := transport.Breakers()[0]
+	if status.State != StateClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("breaker is %s with %d failures, want it closed with none", status.State, status.ConsecutiveFailures)
+	}
+}
+
+func TestConfigBudget(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		want time.Duration
+	}{
+		{name: "unbounded attempts", cfg: Config{MaxRetries: 3, BaseBackoff: time.Second}, want: 0},
+		{name: "single attempt", cfg: Config{AttemptTimeout: 3 * time.Second}, want: 3 * time.Second},
+		{
+			name: "backoffs are capped",
+			cfg:  Config{MaxRetries: 3, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, AttemptTimeout: 3 * time.Second},
+			want: 12*time.Second + 200*time.Millisecond + 400*time.Millisecond + 500*time.Millisecond,
+		},
+		{
+			name: "fixed backoff",
+			cfg:  Config{MaxRetries: 2, MaxBackoff: time.Second, AttemptTimeout: time.Second},
+			want: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.cfg.Budget(); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}